@@ -1,8 +1,14 @@
 package backend
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
 	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/encryption"
 	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
 	"github.com/spf13/viper"
 )
 
@@ -13,14 +19,19 @@ type Backend interface {
 	CreateItem(i *models.Item) (*models.Item, error)
 	ListAllItems() ([]*models.Item, error)
 	GetItemByID(id int) (*models.Item, error)
-	UpdateItemByID(id int, i *models.Item) (*models.Item, error)
+	UpdateItemByID(id int, p *models.ItemPatch) (*models.Item, error)
+	// Removes the item permanently
 	RemoveItemByID(id int) (*models.Item, error)
+	TrashItemByID(id int) (*models.Item, error)
+	RestoreItemByID(id int) (*models.Item, error)
 
 	PublicKeys() ([]string, error)
 	AddPublicKeys(pubKeys ...string) error
 	RemovePublicKeys(pubKeys ...string) error
 
 	Flush() error
+	// Releases the database without writing the changes
+	Close() error
 }
 
 const (
@@ -29,6 +40,48 @@ const (
 	BackendSqlite = "sqlite3"
 )
 
+// Open initializes a backend of type typ for the database at dbPath,
+// the database is created if it doesn't exist.
+func Open(typ, dbPath string) (Backend, error) {
+	var b Backend
+	switch typ {
+	case BackendJSON:
+		b = &JSONBackend{}
+	case BackendSqlite:
+		b = &SqliteBackend{}
+	default:
+		return nil, fmt.Errorf("unknown backend %q, use one of %s, %s", typ, BackendJSON, BackendSqlite)
+	}
+	err := b.Init(&config.Config{
+		DatabasePath: dbPath,
+		Backend:      typ,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Type returns the backend type from the config.
+// RemoveDatabase removes the database at dbPath with its lock file and
+// the journal files of sqlite, the backups are kept.
+func RemoveDatabase(dbPath string) error {
+	var errs []error
+	for _, file := range []string{dbPath, lockPath(dbPath), dbPath + "-journal", dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func Type() string {
+	if viper.GetString(backendType) == BackendJSON {
+		return BackendJSON
+	}
+	return BackendSqlite
+}
+
 func Get() (Backend, error) {
 	if b := viper.Get("__backend_object"); b != nil {
 		return b.(Backend), nil
@@ -45,3 +98,54 @@ func Get() (Backend, error) {
 	viper.Set("__backend_object", bknd)
 	return bknd, nil
 }
+
+// addKeys returns current with pubKeys appended. Every key must be valid
+// and must not already be present.
+func addKeys(current []string, pubKeys ...string) ([]string, error) {
+	out := append([]string(nil), current...)
+	for _, k := range pubKeys {
+		if err := encryption.ValidatePublicKey(k); err != nil {
+			return nil, err
+		}
+		for _, c := range out {
+			if c == k {
+				return nil, fmt.Errorf("public key already exists: %s", k)
+			}
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// removeKeys returns current without pubKeys. Every key must be present
+// and at least one key must remain.
+func removeKeys(current []string, pubKeys ...string) ([]string, error) {
+	out := append([]string(nil), current...)
+	for _, k := range pubKeys {
+		idx := -1
+		for i, c := range out {
+			if c == k {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, fmt.Errorf("public key not found: %s", k)
+		}
+		out = append(out[:idx], out[idx+1:]...)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("can't remove all the public keys")
+	}
+	return out, nil
+}
+
+// setPublicKeys makes pubKeys the recipients of every encrypted field
+// marshalled afterwards. The returned function restores the previous keys.
+func setPublicKeys(pubKeys []string) (restore func()) {
+	old := viper.GetStringSlice(vkeys.PublicKeys)
+	viper.Set(vkeys.PublicKeys, pubKeys)
+	return func() {
+		viper.Set(vkeys.PublicKeys, old)
+	}
+}
@@ -0,0 +1,391 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+	"xorm.io/xorm"
+)
+
+var backendTypes = []string{BackendJSON, BackendSqlite}
+
+func newTestIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("Failed to create age x25519 identity:", err)
+	}
+	return id
+}
+
+// useIdentities makes ids the keys used to encrypt and decrypt items.
+func useIdentities(t *testing.T, ids ...*age.X25519Identity) {
+	t.Helper()
+	var pubKeys, privKeys []string
+	for _, id := range ids {
+		pubKeys = append(pubKeys, id.Recipient().String())
+		privKeys = append(privKeys, id.String())
+	}
+	viper.Set(vkeys.PublicKeys, pubKeys)
+	viper.Set(vkeys.PrivateKeys, privKeys)
+	// The master password isn't asked for
+	viper.Set(vkeys.Password, "test")
+	t.Cleanup(func() {
+		viper.Set(vkeys.PublicKeys, nil)
+		viper.Set(vkeys.PrivateKeys, nil)
+		viper.Set(vkeys.Password, nil)
+	})
+}
+
+// newTestBackend creates an empty database of type typ encrypted for id.
+func newTestBackend(t *testing.T, typ string, id *age.X25519Identity) (Backend, string) {
+	t.Helper()
+	useIdentities(t, id)
+	file := filepath.Join(t.TempDir(), "db")
+	b, err := Open(typ, file)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	if err := b.AddPublicKeys(id.Recipient().String()); err != nil {
+		t.Fatal("Failed to add public key:", err)
+	}
+	return b, file
+}
+
+func reopen(t *testing.T, typ string, b Backend, file string) Backend {
+	t.Helper()
+	if err := b.Flush(); err != nil {
+		t.Fatal("Failed to flush:", err)
+	}
+	b, err := Open(typ, file)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	return b
+}
+
+func newPasswordItem(title, password string) *models.Item {
+	return &models.Item{
+		Title:     title,
+		Namespace: "default",
+		Type:      models.ItemPassword,
+		Password:  &models.PasswordItem{Username: "me", SiteName: title, Password: models.AsymSecretStr(password)},
+	}
+}
+
+func TestRemoveItemByID(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			b, file := newTestBackend(t, typ, newTestIdentity(t))
+			for _, title := range []string{"a", "b", "c"} {
+				if _, err := b.CreateItem(newPasswordItem(title, title+"-secret")); err != nil {
+					t.Fatal("Failed to create item:", err)
+				}
+			}
+			removed, err := b.RemoveItemByID(1)
+			if err != nil {
+				t.Fatal("Failed to remove item:", err)
+			}
+			if removed.Title != "a" {
+				t.Fatal("Removed the wrong item:", removed.Title)
+			}
+			if _, err := b.RemoveItemByID(1); !errors.Is(err, models.ErrItemNotFound) {
+				t.Fatal("Expected ErrItemNotFound, found:", err)
+			}
+			// The id of a removed item must not collide with the remaining ones
+			d, err := b.CreateItem(newPasswordItem("d", "d-secret"))
+			if err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			if d.ID == 2 || d.ID == 3 {
+				t.Fatal("Reused the id of an existing item:", d.ID)
+			}
+
+			b = reopen(t, typ, b, file)
+			defer b.Flush()
+			items, err := b.ListAllItems()
+			if err != nil {
+				t.Fatal("Failed to list items:", err)
+			}
+			if len(items) != 3 {
+				t.Fatal("Expected 3 items, found", len(items))
+			}
+			for id, title := range map[int]string{2: "b", 3: "c", d.ID: "d"} {
+				i, err := b.GetItemByID(id)
+				if err != nil {
+					t.Fatal("Failed to get item:", err)
+				}
+				if i.Title != title || string(i.Password.Password) != title+"-secret" {
+					t.Fatalf("Item %d is %q, expected %q", id, i.Title, title)
+				}
+			}
+			if _, err := b.GetItemByID(1); !errors.Is(err, models.ErrItemNotFound) {
+				t.Fatal("Removed item is still found:", err)
+			}
+		})
+	}
+}
+
+func TestUpdateItemByID(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			b, file := newTestBackend(t, typ, newTestIdentity(t))
+			i, err := b.CreateItem(&models.Item{
+				Title:     "server",
+				Namespace: "default",
+				Type:      models.ItemSSH,
+				SSH:       &models.SSHItem{Host: "example.com", Port: 22, Username: "root", Password: "old"},
+			})
+			if err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			title, user, pass := "web", "admin", models.AsymSecretStr("new")
+			port := uint16(2222)
+			_, err = b.UpdateItemByID(i.ID, &models.ItemPatch{
+				Title: &title,
+				SSH:   &models.SSHItemPatch{Username: &user, Port: &port, Password: &pass},
+			})
+			if err != nil {
+				t.Fatal("Failed to update item:", err)
+			}
+			if _, err := b.UpdateItemByID(i.ID+1, &models.ItemPatch{Title: &title}); !errors.Is(err, models.ErrItemNotFound) {
+				t.Fatal("Expected ErrItemNotFound, found:", err)
+			}
+
+			b = reopen(t, typ, b, file)
+			defer b.Flush()
+			i, err = b.GetItemByID(i.ID)
+			if err != nil {
+				t.Fatal("Failed to get item:", err)
+			}
+			if i.Title != title || i.SSH.Host != "example.com" || i.SSH.Username != user || i.SSH.Port != port {
+				t.Fatal("Unexpected item:", i.Title, i.SSH.Host, i.SSH.Username, i.SSH.Port)
+			}
+			if i.SSH.Password != pass {
+				t.Fatal("Password isn't updated:", i.SSH.Password)
+			}
+		})
+	}
+}
+
+func TestOpenSkipDecryption(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			b, file := newTestBackend(t, typ, newTestIdentity(t))
+			if _, err := b.CreateItem(newPasswordItem("a", "a-secret")); err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			if err := b.Flush(); err != nil {
+				t.Fatal("Failed to flush:", err)
+			}
+
+			// Neither the master password nor the private keys are available
+			viper.Set(vkeys.Password, nil)
+			viper.Set(vkeys.PrivateKeys, nil)
+			viper.Set(vkeys.SkipDecryption, true)
+			defer viper.Set(vkeys.SkipDecryption, false)
+			b, err := Open(typ, file)
+			if err != nil {
+				t.Fatal("Failed to open backend without the secrets:", err)
+			}
+			defer b.Close()
+			items, err := b.ListAllItems()
+			if err != nil {
+				t.Fatal("Failed to list items:", err)
+			}
+			if len(items) != 1 || items[0].Title != "a" {
+				t.Fatal("Unexpected items:", items)
+			}
+			if items[0].Password.Password != "" {
+				t.Fatal("Secret is decrypted:", items[0].Password.Password)
+			}
+		})
+	}
+}
+
+func TestRemoveLastItemID(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			b, file := newTestBackend(t, typ, newTestIdentity(t))
+			for _, title := range []string{"a", "b"} {
+				if _, err := b.CreateItem(newPasswordItem(title, title+"-secret")); err != nil {
+					t.Fatal("Failed to create item:", err)
+				}
+			}
+			if _, err := b.RemoveItemByID(2); err != nil {
+				t.Fatal("Failed to remove item:", err)
+			}
+			// The highest id is remembered across writes
+			b = reopen(t, typ, b, file)
+			defer b.Flush()
+			c, err := b.CreateItem(newPasswordItem("c", "c-secret"))
+			if err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			if c.ID != 3 {
+				t.Fatal("Expected id 3 for the new item, found", c.ID)
+			}
+		})
+	}
+}
+
+func TestRemoveDatabase(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			b, file := newTestBackend(t, typ, newTestIdentity(t))
+			if _, err := b.CreateItem(newPasswordItem("a", "a-secret")); err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			if err := b.Close(); err != nil {
+				t.Fatal("Failed to close:", err)
+			}
+			// Left by an interrupted sqlite transaction
+			if err := os.WriteFile(file+"-journal", nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := RemoveDatabase(file); err != nil {
+				t.Fatal("Failed to remove database:", err)
+			}
+			entries, err := os.ReadDir(filepath.Dir(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				t.Error("File is left:", e.Name())
+			}
+		})
+	}
+}
+
+// readAllWith reads every item of the database with only the private key
+// of id.
+func readAllWith(t *testing.T, typ, file string, id *age.X25519Identity) ([]*models.Item, error) {
+	t.Helper()
+	old := viper.GetStringSlice(vkeys.PrivateKeys)
+	viper.Set(vkeys.PrivateKeys, []string{id.String()})
+	defer viper.Set(vkeys.PrivateKeys, old)
+	b, err := Open(typ, file)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+	return b.ListAllItems()
+}
+
+func checkItems(t *testing.T, items []*models.Item, err error, titles ...string) {
+	t.Helper()
+	if err != nil {
+		t.Fatal("Failed to read items:", err)
+	}
+	if len(items) != len(titles) {
+		t.Fatalf("Expected %d items, found %d", len(titles), len(items))
+	}
+	for idx, i := range items {
+		if i.Title != titles[idx] || string(i.Password.Password) != titles[idx]+"-secret" {
+			t.Fatalf("Unexpected item %q with password %q", i.Title, i.Password.Password)
+		}
+	}
+}
+
+func TestPublicKeysReencrypt(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			id1, id2 := newTestIdentity(t), newTestIdentity(t)
+			b, file := newTestBackend(t, typ, id1)
+			for _, title := range []string{"a", "b"} {
+				if _, err := b.CreateItem(newPasswordItem(title, title+"-secret")); err != nil {
+					t.Fatal("Failed to create item:", err)
+				}
+			}
+			if err := b.AddPublicKeys(id2.Recipient().String()); err != nil {
+				t.Fatal("Failed to add public key:", err)
+			}
+			if err := b.Flush(); err != nil {
+				t.Fatal("Failed to flush:", err)
+			}
+			for _, id := range []*age.X25519Identity{id1, id2} {
+				items, err := readAllWith(t, typ, file, id)
+				checkItems(t, items, err, "a", "b")
+			}
+
+			b, err := Open(typ, file)
+			if err != nil {
+				t.Fatal("Failed to open backend:", err)
+			}
+			if err := b.RemovePublicKeys(id1.Recipient().String()); err != nil {
+				t.Fatal("Failed to remove public key:", err)
+			}
+			if err := b.Flush(); err != nil {
+				t.Fatal("Failed to flush:", err)
+			}
+			items, err := readAllWith(t, typ, file, id2)
+			checkItems(t, items, err, "a", "b")
+			if _, err := readAllWith(t, typ, file, id1); err == nil {
+				t.Fatal("Removed key still decrypts the items")
+			}
+		})
+	}
+}
+
+func TestPublicKeysReencryptFailure(t *testing.T) {
+	// Both fail after the new key is stored, while the items are encrypted
+	fail := map[string]func(b Backend, pubKey string) error{
+		BackendJSON: func(b Backend, pubKey string) error {
+			if err := b.AddPublicKeys(pubKey); err != nil {
+				return err
+			}
+			// Secrets can't be encrypted without decrypting them first
+			viper.Set(vkeys.SkipDecryption, true)
+			defer viper.Set(vkeys.SkipDecryption, false)
+			err := b.Flush()
+			b.Close()
+			return err
+		},
+		BackendSqlite: func(b Backend, pubKey string) error {
+			defer b.Close()
+			sb := b.(*SqliteBackend)
+			keys := append(viper.GetStringSlice(vkeys.PublicKeys), pubKey, "age1invalid")
+			return sb.updatePublicKeys(keys, func(s *xorm.Session) error {
+				_, err := s.Insert(&PublicKey{Key: pubKey})
+				return err
+			})
+		},
+	}
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			id1, id2 := newTestIdentity(t), newTestIdentity(t)
+			b, file := newTestBackend(t, typ, id1)
+			if _, err := b.CreateItem(newPasswordItem("a", "a-secret")); err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			b = reopen(t, typ, b, file)
+			if err := fail[typ](b, id2.Recipient().String()); err == nil {
+				t.Fatal("Expected re-encryption to fail")
+			}
+			if typ == BackendSqlite {
+				if got := viper.GetStringSlice(vkeys.PublicKeys); len(got) != 1 || got[0] != id1.Recipient().String() {
+					t.Fatal("Public keys are not restored:", got)
+				}
+			}
+
+			b, err := Open(typ, file)
+			if err != nil {
+				t.Fatal("Failed to open backend:", err)
+			}
+			keys, err := b.PublicKeys()
+			b.Close()
+			if err != nil || len(keys) != 1 || keys[0] != id1.Recipient().String() {
+				t.Fatal("Unexpected public keys:", keys, err)
+			}
+			items, err := readAllWith(t, typ, file, id1)
+			checkItems(t, items, err, "a")
+		})
+	}
+}
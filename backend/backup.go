@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riadafridishibly/mypass/config"
+)
+
+type Backup struct {
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// Backups of a database are stored next to it as <name>-<timestamp>.
+func backupPrefix(dbPath string) string {
+	return filepath.Base(dbPath) + "-"
+}
+
+// Older versions named the backups db-<unix seconds> whatever the name of
+// the database was.
+const legacyBackupPrefix = "db-"
+
+// backupTime returns when the backup file name of the database is
+// created, ok is false if it's not a backup of the database.
+func backupTime(dbPath, name string) (t time.Time, ok bool) {
+	prefix := backupPrefix(dbPath)
+	if !strings.HasPrefix(name, prefix) {
+		prefix = legacyBackupPrefix
+	}
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(strings.TrimPrefix(name, prefix), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// Older versions used seconds
+	if ts < 1e12 {
+		return time.Unix(ts, 0), true
+	}
+	if prefix == legacyBackupPrefix && prefix != backupPrefix(dbPath) {
+		// A backup of another database named db
+		return time.Time{}, false
+	}
+	return time.Unix(0, ts), true
+}
+
+// ListBackups returns the backups of the database, newest first.
+func ListBackups(dbPath string) ([]Backup, error) {
+	dir := filepath.Dir(dbPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []Backup
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		createdAt, ok := backupTime(dbPath, e.Name())
+		if !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Backup{
+			Path:      filepath.Join(dir, e.Name()),
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+// CreateBackup copies the database to a new backup and removes the oldest
+// ones, so at most keep backups are left. Nothing is done if keep <= 0 or
+// the database doesn't exist yet.
+func CreateBackup(dbPath string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	data, err := os.ReadFile(dbPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	backup := filepath.Join(filepath.Dir(dbPath), fmt.Sprintf("%s%d", backupPrefix(dbPath), time.Now().UnixNano()))
+	if err := config.WriteFileAtomic(backup, data, 0600); err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	return pruneBackups(dbPath, keep)
+}
+
+func pruneBackups(dbPath string, keep int) error {
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= keep {
+		return nil
+	}
+	for _, b := range backups[keep:] {
+		if err := os.Remove(b.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreBackup replaces the database with the backup. The current
+// database is backed up first, so a restore can be undone.
+func RestoreBackup(dbPath string, b Backup, keep int) error {
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+	// Other processes must not write between the safety backup and the
+	// restore, their changes would be lost from both
+	return WithLock(dbPath, func() error {
+		// Keep the backup we restore from even if it's the oldest one
+		if err := CreateBackup(dbPath, keep+1); err != nil {
+			return err
+		}
+		return config.WriteFileAtomic(dbPath, data, 0600)
+	})
+}
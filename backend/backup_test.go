@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, file, data string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+}
+
+func readTestFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal("Failed to read file:", err)
+	}
+	return string(data)
+}
+
+// backupContents returns the contents of the backups, newest first.
+func backupContents(t *testing.T, dbPath string) []string {
+	t.Helper()
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		t.Fatal("Failed to list backups:", err)
+	}
+	var out []string
+	for _, b := range backups {
+		out = append(out, readTestFile(t, b.Path))
+	}
+	return out
+}
+
+func TestCreateBackup(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+	// Nothing to back up yet
+	if err := CreateBackup(dbPath, 3); err != nil {
+		t.Fatal("Failed to back up a missing database:", err)
+	}
+	for i := 1; i <= 5; i++ {
+		writeTestFile(t, dbPath, fmt.Sprint("v", i))
+		if err := CreateBackup(dbPath, 3); err != nil {
+			t.Fatal("Failed to create backup:", err)
+		}
+	}
+	// Backups disabled
+	if err := CreateBackup(dbPath, 0); err != nil {
+		t.Fatal("Failed to create backup:", err)
+	}
+	got := fmt.Sprint(backupContents(t, dbPath))
+	if want := fmt.Sprint([]string{"v5", "v4", "v3"}); got != want {
+		t.Fatalf("Expected backups %s, found %s", want, got)
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+	for _, data := range []string{"v1", "v2"} {
+		writeTestFile(t, dbPath, data)
+		if err := CreateBackup(dbPath, 2); err != nil {
+			t.Fatal("Failed to create backup:", err)
+		}
+	}
+	writeTestFile(t, dbPath, "v3")
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		t.Fatal("Failed to list backups:", err)
+	}
+	// Restore the oldest one, it must survive the safety backup
+	if err := RestoreBackup(dbPath, backups[len(backups)-1], 2); err != nil {
+		t.Fatal("Failed to restore backup:", err)
+	}
+	if got := readTestFile(t, dbPath); got != "v1" {
+		t.Fatal("Expected the restored database v1, found", got)
+	}
+	got := fmt.Sprint(backupContents(t, dbPath))
+	if want := fmt.Sprint([]string{"v3", "v2", "v1"}); got != want {
+		t.Fatalf("Expected backups %s, found %s", want, got)
+	}
+
+	// The restore is undone with the safety backup
+	backups, err = ListBackups(dbPath)
+	if err != nil {
+		t.Fatal("Failed to list backups:", err)
+	}
+	if err := RestoreBackup(dbPath, backups[0], 2); err != nil {
+		t.Fatal("Failed to restore backup:", err)
+	}
+	if got := readTestFile(t, dbPath); got != "v3" {
+		t.Fatal("Expected the restored database v3, found", got)
+	}
+}
+
+func TestLegacyBackups(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "db.json")
+	writeTestFile(t, filepath.Join(dir, "db-1690000000"), "old1")
+	writeTestFile(t, filepath.Join(dir, "db-1690000100"), "old2")
+	// Backups of another database named db are left alone
+	writeTestFile(t, filepath.Join(dir, fmt.Sprint("db-", int64(1790000000)*1e9)), "other")
+	writeTestFile(t, dbPath, "v1")
+	if err := CreateBackup(dbPath, 3); err != nil {
+		t.Fatal("Failed to create backup:", err)
+	}
+	got := fmt.Sprint(backupContents(t, dbPath))
+	if want := fmt.Sprint([]string{"v1", "old2", "old1"}); got != want {
+		t.Fatalf("Expected backups %s, found %s", want, got)
+	}
+	writeTestFile(t, dbPath, "v2")
+	if err := CreateBackup(dbPath, 2); err != nil {
+		t.Fatal("Failed to create backup:", err)
+	}
+	got = fmt.Sprint(backupContents(t, dbPath))
+	if want := fmt.Sprint([]string{"v2", "v1"}); got != want {
+		t.Fatalf("Expected backups %s, found %s", want, got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, fmt.Sprint("db-", int64(1790000000)*1e9))); got != "other" {
+		t.Fatal("Backup of another database is changed:", got)
+	}
+}
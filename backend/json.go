@@ -1,14 +1,14 @@
 package backend
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"os"
-	"path/filepath"
-	"time"
 
 	"github.com/riadafridishibly/mypass/config"
 	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/version"
 	"github.com/riadafridishibly/mypass/vkeys"
 	jww "github.com/spf13/jwalterweatherman"
 	"github.com/spf13/viper"
@@ -17,16 +17,62 @@ import (
 type JSONBackend struct {
 	file string
 	db   *models.Database
+	// Version of mypass which created the database
+	createdBy string
+	// The file as it was loaded, nil if it didn't exist
+	loaded []byte
+	// Changes since the database was loaded, Flush applies them again if
+	// another process has written the file meanwhile
+	changes []func(db *models.Database) error
+}
+
+// change applies f to the loaded database and records it for Flush.
+func (jb *JSONBackend) change(f func(db *models.Database) error) error {
+	if err := f(jb.db); err != nil {
+		return err
+	}
+	jb.changes = append(jb.changes, f)
+	return nil
+}
+
+// changeItem is change for the modifications of a single item.
+func (jb *JSONBackend) changeItem(f func(db *models.Database) (*models.Item, error)) (*models.Item, error) {
+	var out *models.Item
+	err := jb.change(func(db *models.Database) error {
+		i, err := f(db)
+		out = i
+		return err
+	})
+	return out, err
 }
 
 // AddPublicKeys implements Backend
-func (*JSONBackend) AddPublicKeys(pubKeys ...string) error {
-	panic("unimplemented")
+//
+// Items are kept decrypted in memory, they are encrypted again
+// for the new set of keys on Flush.
+func (jb *JSONBackend) AddPublicKeys(pubKeys ...string) error {
+	return jb.change(func(db *models.Database) error {
+		keys, err := addKeys(db.PublicKeys, pubKeys...)
+		if err != nil {
+			return err
+		}
+		db.PublicKeys = keys
+		setPublicKeys(keys)
+		return nil
+	})
 }
 
 // RemovePublicKeys implements Backend
-func (*JSONBackend) RemovePublicKeys(pubKeys ...string) error {
-	panic("unimplemented")
+func (jb *JSONBackend) RemovePublicKeys(pubKeys ...string) error {
+	return jb.change(func(db *models.Database) error {
+		keys, err := removeKeys(db.PublicKeys, pubKeys...)
+		if err != nil {
+			return err
+		}
+		db.PublicKeys = keys
+		setPublicKeys(keys)
+		return nil
+	})
 }
 
 // PublicKeys implements Backend
@@ -43,13 +89,61 @@ func newJSONBackend() (Backend, error) {
 }
 
 // Flush implements Backend
+//
+// The database is locked only while it's written. If another process has
+// changed the file since it was loaded, the changes are applied again to
+// the current file.
 func (jb *JSONBackend) Flush() error {
-	return jb.save()
+	if len(jb.changes) == 0 {
+		return nil
+	}
+	l, err := lockFile(lockPath(jb.file), viper.GetDuration(vkeys.LockTimeout))
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+	data, err := os.ReadFile(jb.file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !bytes.Equal(data, jb.loaded) {
+		jww.INFO.Println("database is changed by another process, applying the changes again")
+		db, createdBy, _, err := jb.load()
+		if err != nil {
+			return err
+		}
+		for _, f := range jb.changes {
+			if err := f(db); err != nil {
+				return err
+			}
+		}
+		jb.db, jb.createdBy = db, createdBy
+	}
+	data, err = jb.save()
+	if err != nil {
+		return err
+	}
+	jb.loaded, jb.changes = data, nil
+	return nil
+}
+
+// Close implements Backend
+func (jb *JSONBackend) Close() error {
+	jb.changes = nil
+	return nil
 }
 
 // CreateItem implements Backend
 func (jb *JSONBackend) CreateItem(i *models.Item) (*models.Item, error) {
-	return jb.db.AddItem(i)
+	// The id is given again if the changes are applied to a newer file,
+	// unless it's set by the caller
+	given := i.ID != 0
+	return jb.changeItem(func(db *models.Database) (*models.Item, error) {
+		if !given {
+			i.ID = 0
+		}
+		return db.AddItem(i)
+	})
 }
 
 // GetItemByID implements Backend
@@ -58,26 +152,44 @@ func (jb *JSONBackend) GetItemByID(id int) (*models.Item, error) {
 }
 
 // Init implements Backend
-func (jb *JSONBackend) Init(cfg *config.Config) error {
+func (jb *JSONBackend) Init(cfg *config.Config) (err error) {
 	jb.file = cfg.DatabasePath
-	currentData, err := os.ReadFile(cfg.DatabasePath)
+	// Private keys are encrypted with the master password,
+	// ask for it before blocking other processes. It's not needed
+	// if the secrets are left encrypted.
+	if !viper.GetBool(vkeys.SkipDecryption) {
+		err = config.LoadCachedPassword()
+		if err != nil {
+			return err
+		}
+	}
+	// Don't read a file which is being written, the lock isn't held
+	// afterwards so other processes aren't blocked while we run
+	l, err := lockFile(lockPath(cfg.DatabasePath), viper.GetDuration(vkeys.LockTimeout))
 	if err != nil {
-		jww.ERROR.Println("Failed to open database file")
 		return err
 	}
-	// FIXME: we may lose data if we fail to write again
-	// Create a backup for now
-	dir, _ := filepath.Split(cfg.DatabasePath)
-	backupDb := filepath.Join(dir, fmt.Sprintf("db-%d", time.Now().Unix()))
-	err = os.WriteFile(backupDb, currentData, 0600)
+	defer l.Unlock()
+	jb.db, jb.createdBy, jb.loaded, err = jb.load()
+	return err
+}
+
+// load reads the database, it must be called with the lock held.
+func (jb *JSONBackend) load() (db *models.Database, createdBy string, data []byte, err error) {
+	data, err = os.ReadFile(jb.file)
+	if errors.Is(err, os.ErrNotExist) {
+		// Will be created on Flush
+		return &models.Database{}, version.Version, nil, nil
+	}
 	if err != nil {
-		return err
+		return nil, "", nil, err
 	}
-	jb.db, err = config.LoadDatabase()
+	f, err := config.ReadDatabaseFile(jb.file)
 	if err != nil {
-		return err
+		jww.ERROR.Println("Failed to open database file")
+		return nil, "", nil, err
 	}
-	return err
+	return f.Database, f.CreatedBy, data, nil
 }
 
 // ListAllItems implements Backend
@@ -87,21 +199,48 @@ func (jb *JSONBackend) ListAllItems() ([]*models.Item, error) {
 
 // RemoveItemByID implements Backend
 func (jb *JSONBackend) RemoveItemByID(id int) (*models.Item, error) {
-	return jb.db.RemoveItem(id)
+	return jb.changeItem(func(db *models.Database) (*models.Item, error) {
+		return db.RemoveItem(id)
+	})
+}
+
+// TrashItemByID implements Backend
+func (jb *JSONBackend) TrashItemByID(id int) (*models.Item, error) {
+	return jb.changeItem(func(db *models.Database) (*models.Item, error) {
+		return db.TrashItem(id)
+	})
+}
+
+// RestoreItemByID implements Backend
+func (jb *JSONBackend) RestoreItemByID(id int) (*models.Item, error) {
+	return jb.changeItem(func(db *models.Database) (*models.Item, error) {
+		return db.RestoreItem(id)
+	})
 }
 
 // UpdateItemByID implements Backend
-func (jb *JSONBackend) UpdateItemByID(id int, i *models.Item) (*models.Item, error) {
-	return jb.db.UpdateItem(id, i)
+func (jb *JSONBackend) UpdateItemByID(id int, p *models.ItemPatch) (*models.Item, error) {
+	return jb.changeItem(func(db *models.Database) (*models.Item, error) {
+		return db.UpdateItem(id, p)
+	})
 }
 
 var _ Backend = (*JSONBackend)(nil)
 
-func (jb *JSONBackend) save() error {
-	data, err := json.Marshal(jb.db)
+// save writes the database and returns what is written, it must be
+// called with the lock held.
+func (jb *JSONBackend) save() ([]byte, error) {
+	data, err := json.Marshal(&models.DatabaseFile{
+		Magic:     models.DatabaseMagic,
+		Version:   models.DatabaseVersion,
+		CreatedBy: jb.createdBy,
+		Database:  jb.db,
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := CreateBackup(jb.file, viper.GetInt(vkeys.Backups)); err != nil {
+		return nil, err
 	}
-	// maybe rewind here?
-	return os.WriteFile(jb.file, data, 0600)
+	return data, config.WriteFileAtomic(jb.file, data, 0600)
 }
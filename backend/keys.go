@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"filippo.io/age"
+	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/encryption"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+// StartKeyRotation appends a new private key to keysFile and records the
+// rotation in it. If a rotation is already in progress it's returned with
+// resumed set instead, retire must match the one it's started with.
+func StartKeyRotation(dbPath, keysFile string, retire bool) (rotation *models.KeyRotation, resumed bool, err error) {
+	// The file is read again under the lock, so a concurrent passwd
+	// doesn't lose its change
+	err = WithLock(dbPath, func() error {
+		privKeys, err := config.ReadPrivateKeys(keysFile)
+		if err != nil {
+			return err
+		}
+		if r := privKeys.Rotation; r != nil {
+			if r.Retire != retire {
+				return fmt.Errorf("key rotation started at %s with retire=%t is in progress, resume it with the same retire setting",
+					r.StartedAt.Format(time.RFC3339), r.Retire)
+			}
+			rotation, resumed = r, true
+			return nil
+		}
+		identity, err := age.GenerateX25519Identity()
+		if err != nil {
+			return fmt.Errorf("failed to create X25519 Key pairs: %v", err)
+		}
+		privKeys.Keys = append(privKeys.Keys, models.SymSecretStr(identity.String()))
+		privKeys.Rotation = &models.KeyRotation{
+			StartedAt: time.Now(),
+			Retire:    retire,
+		}
+		privKeys.Meta.UpdatedAt = time.Now()
+		rotation = privKeys.Rotation
+		// Persist the new key before any item is encrypted for it
+		return config.WritePrivateKeys(keysFile, privKeys)
+	})
+	return rotation, resumed, err
+}
+
+// FinishKeyRotation encrypts every item of the database for the new key of
+// the rotation in keysFile and retires the older keys if requested. Every
+// step can be repeated, so an interrupted rotation is completed by running
+// it again.
+func FinishKeyRotation(typ, dbPath, keysFile string) (newPub string, retired []string, err error) {
+	privKeys, err := config.ReadPrivateKeys(keysFile)
+	if err != nil {
+		return "", nil, err
+	}
+	if privKeys.Rotation == nil {
+		return "", nil, errors.New("no key rotation in progress")
+	}
+	retire := privKeys.Rotation.Retire
+	var all []string
+	for _, k := range privKeys.Keys {
+		all = append(all, string(k))
+	}
+	// Items may be encrypted for any of the keys at this point
+	viper.Set(vkeys.PrivateKeys, all)
+	pubs, err := encryption.PublicKeysOf(all...)
+	if err != nil {
+		return "", nil, err
+	}
+	newPub, oldPubs := pubs[len(pubs)-1], pubs[:len(pubs)-1]
+
+	b, err := Open(typ, dbPath)
+	if err != nil {
+		return "", nil, err
+	}
+	retired, err = rotatePublicKeys(b, newPub, oldPubs, retire)
+	if err != nil {
+		b.Close()
+		return "", nil, err
+	}
+	if err := b.Flush(); err != nil {
+		return "", nil, err
+	}
+
+	err = WithLock(dbPath, func() error {
+		privKeys, err := config.ReadPrivateKeys(keysFile)
+		if err != nil {
+			return err
+		}
+		if privKeys.Rotation == nil {
+			// Finished by another process
+			return nil
+		}
+		if last := privKeys.Keys[len(privKeys.Keys)-1]; string(last) != all[len(all)-1] {
+			return errors.New("private keys are changed during the rotation")
+		}
+		if retire {
+			privKeys.Keys = privKeys.Keys[len(privKeys.Keys)-1:]
+		}
+		privKeys.Rotation = nil
+		privKeys.Meta.UpdatedAt = time.Now()
+		return config.WritePrivateKeys(keysFile, privKeys)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if retire {
+		viper.Set(vkeys.PrivateKeys, all[len(all)-1:])
+	}
+	return newPub, retired, nil
+}
+
+// rotatePublicKeys adds newPub to the public keys of the database and
+// removes the old ones if retire is set, keys already changed by an
+// interrupted rotation are skipped.
+func rotatePublicKeys(b Backend, newPub string, oldPubs []string, retire bool) ([]string, error) {
+	current, err := b.PublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	// Items are written again for these, unless they are changed below
+	viper.Set(vkeys.PublicKeys, current)
+	has := func(k string) bool {
+		for _, c := range current {
+			if c == k {
+				return true
+			}
+		}
+		return false
+	}
+	if !has(newPub) {
+		if err := b.AddPublicKeys(newPub); err != nil {
+			return nil, err
+		}
+	}
+	if !retire {
+		return nil, nil
+	}
+	var retired []string
+	for _, k := range oldPubs {
+		if has(k) {
+			retired = append(retired, k)
+		}
+	}
+	if err := b.RemovePublicKeys(retired...); err != nil {
+		return nil, err
+	}
+	return retired, nil
+}
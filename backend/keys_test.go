@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/encryption"
+	"github.com/riadafridishibly/mypass/models"
+)
+
+// newTestKeysFile writes the private key file of a vault with the keys.
+func newTestKeysFile(t *testing.T, ids ...*age.X25519Identity) string {
+	t.Helper()
+	encryption.ScryptWorkFactor = 10
+	t.Cleanup(func() { encryption.ScryptWorkFactor = 18 })
+	file := filepath.Join(t.TempDir(), "private_keys")
+	var keys []models.SymSecretStr
+	for _, id := range ids {
+		keys = append(keys, models.SymSecretStr(id.String()))
+	}
+	if err := config.WritePrivateKeys(file, &models.PrivateKeys{Keys: keys}); err != nil {
+		t.Fatal("Failed to write private keys:", err)
+	}
+	return file
+}
+
+func readTestKeys(t *testing.T, file string) (*models.PrivateKeys, []*age.X25519Identity) {
+	t.Helper()
+	privKeys, err := config.ReadPrivateKeys(file)
+	if err != nil {
+		t.Fatal("Failed to read private keys:", err)
+	}
+	var ids []*age.X25519Identity
+	for _, k := range privKeys.Keys {
+		id, err := age.ParseX25519Identity(string(k))
+		if err != nil {
+			t.Fatal("Invalid private key:", err)
+		}
+		ids = append(ids, id)
+	}
+	return privKeys, ids
+}
+
+func TestRotateKeys(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			id1 := newTestIdentity(t)
+			b, dbPath := newTestBackend(t, typ, id1)
+			for _, title := range []string{"a", "b"} {
+				if _, err := b.CreateItem(newPasswordItem(title, title+"-secret")); err != nil {
+					t.Fatal("Failed to create item:", err)
+				}
+			}
+			if err := b.Flush(); err != nil {
+				t.Fatal("Failed to flush:", err)
+			}
+			keysFile := newTestKeysFile(t, id1)
+
+			_, resumed, err := StartKeyRotation(dbPath, keysFile, true)
+			if err != nil || resumed {
+				t.Fatal("Failed to start rotation:", resumed, err)
+			}
+			newPub, retired, err := FinishKeyRotation(typ, dbPath, keysFile)
+			if err != nil {
+				t.Fatal("Failed to finish rotation:", err)
+			}
+			if len(retired) != 1 || retired[0] != id1.Recipient().String() {
+				t.Fatal("Unexpected retired keys:", retired)
+			}
+
+			privKeys, ids := readTestKeys(t, keysFile)
+			if privKeys.Rotation != nil || len(ids) != 1 || ids[0].Recipient().String() != newPub {
+				t.Fatalf("Unexpected private keys after rotation: %+v", privKeys)
+			}
+			items, err := readAllWith(t, typ, dbPath, ids[0])
+			checkItems(t, items, err, "a", "b")
+			if _, err := readAllWith(t, typ, dbPath, id1); err == nil {
+				t.Fatal("Retired key still decrypts the items")
+			}
+		})
+	}
+}
+
+func TestRotateKeysResume(t *testing.T) {
+	for _, typ := range backendTypes {
+		t.Run(typ, func(t *testing.T) {
+			id1 := newTestIdentity(t)
+			b, dbPath := newTestBackend(t, typ, id1)
+			if _, err := b.CreateItem(newPasswordItem("a", "a-secret")); err != nil {
+				t.Fatal("Failed to create item:", err)
+			}
+			if err := b.Flush(); err != nil {
+				t.Fatal("Failed to flush:", err)
+			}
+			keysFile := newTestKeysFile(t, id1)
+
+			if _, _, err := StartKeyRotation(dbPath, keysFile, false); err != nil {
+				t.Fatal("Failed to start rotation:", err)
+			}
+			// Interrupted after the items are encrypted for the new key
+			_, ids := readTestKeys(t, keysFile)
+			useIdentities(t, ids...)
+			b, err := Open(typ, dbPath)
+			if err != nil {
+				t.Fatal("Failed to open backend:", err)
+			}
+			if err := b.AddPublicKeys(ids[1].Recipient().String()); err != nil {
+				t.Fatal("Failed to add public key:", err)
+			}
+			if err := b.Flush(); err != nil {
+				t.Fatal("Failed to flush:", err)
+			}
+
+			if _, _, err := StartKeyRotation(dbPath, keysFile, true); err == nil {
+				t.Fatal("Expected error for a different retire than the rotation in progress")
+			}
+			rotation, resumed, err := StartKeyRotation(dbPath, keysFile, false)
+			if err != nil || !resumed || rotation.Retire {
+				t.Fatalf("Expected to resume the rotation: %v %+v %v", resumed, rotation, err)
+			}
+			newPub, _, err := FinishKeyRotation(typ, dbPath, keysFile)
+			if err != nil {
+				t.Fatal("Failed to finish rotation:", err)
+			}
+			privKeys, ids := readTestKeys(t, keysFile)
+			if privKeys.Rotation != nil || len(ids) != 2 || ids[1].Recipient().String() != newPub {
+				t.Fatalf("Unexpected private keys after rotation: %+v", privKeys)
+			}
+			for _, id := range ids {
+				items, err := readAllWith(t, typ, dbPath, id)
+				checkItems(t, items, err, "a")
+			}
+			if _, _, err := FinishKeyRotation(typ, dbPath, keysFile); err == nil {
+				t.Fatal("Expected error without a rotation in progress")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+var ErrLocked = errors.New("vault is locked")
+
+// fileLock is an advisory lock on a sidecar file, it's released by the
+// kernel if the process dies while holding it.
+type fileLock struct {
+	f *os.File
+}
+
+func lockPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+// lockFile takes an exclusive lock on file, waiting up to timeout for
+// another process to release it.
+func lockFile(file string, timeout time.Duration) (*fileLock, error) {
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			holder := readPid(f)
+			f.Close()
+			if holder == 0 {
+				return nil, ErrLocked
+			}
+			return nil, fmt.Errorf("%w by pid %d", ErrLocked, holder)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	// Record the holder, so others can report it
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	return &fileLock{f: f}, nil
+}
+
+// WithLock runs f holding the lock of the database at dbPath, for writes
+// of other files of the vault like the private keys.
+func WithLock(dbPath string, f func() error) error {
+	l, err := lockFile(lockPath(dbPath), viper.GetDuration(vkeys.LockTimeout))
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+	return f()
+}
+
+func readPid(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, _ := strconv.Atoi(string(bytes.TrimSpace(buf[:n])))
+	return pid
+}
+
+func (l *fileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	_ = l.f.Truncate(0)
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
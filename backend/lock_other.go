@@ -0,0 +1,14 @@
+//go:build !unix
+
+package backend
+
+import "os"
+
+// flock is not available, concurrent writes are not guarded.
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,147 @@
+//go:build unix
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+func TestLockFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.lock")
+	l, err := lockFile(file, 0)
+	if err != nil {
+		t.Fatal("Failed to take the lock:", err)
+	}
+	_, err = lockFile(file, 100*time.Millisecond)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatal("Expected ErrLocked, found:", err)
+	}
+	if want := fmt.Sprintf("pid %d", os.Getpid()); err != nil && !strings.Contains(err.Error(), want) {
+		t.Fatalf("Expected %q in error: %v", want, err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal("Failed to release the lock:", err)
+	}
+	l, err = lockFile(file, 0)
+	if err != nil {
+		t.Fatal("Failed to take the released lock:", err)
+	}
+	l.Unlock()
+}
+
+func TestRestoreBackupLocked(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+	writeTestFile(t, dbPath, "v1")
+	if err := CreateBackup(dbPath, 2); err != nil {
+		t.Fatal("Failed to create backup:", err)
+	}
+	writeTestFile(t, dbPath, "v2")
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		t.Fatal("Failed to list backups:", err)
+	}
+
+	l, err := lockFile(lockPath(dbPath), 0)
+	if err != nil {
+		t.Fatal("Failed to take the lock:", err)
+	}
+	defer l.Unlock()
+	viper.Set(vkeys.LockTimeout, 100*time.Millisecond)
+	defer viper.Set(vkeys.LockTimeout, nil)
+	if err := RestoreBackup(dbPath, backups[0], 2); !errors.Is(err, ErrLocked) {
+		t.Fatal("Expected ErrLocked, found:", err)
+	}
+	if got := readTestFile(t, dbPath); got != "v2" {
+		t.Fatal("Database is changed while locked:", got)
+	}
+	if n := len(backupContents(t, dbPath)); n != 1 {
+		t.Fatal("Backup is created while locked, backups:", n)
+	}
+}
+
+func TestMigrateLocked(t *testing.T) {
+	useIdentities(t, newTestIdentity(t))
+	file := filepath.Join(t.TempDir(), "db")
+	e := newTestEngine(t, file)
+	if err := e.Sync(new(baselineItem), new(PublicKey)); err != nil {
+		t.Fatal("Failed to create baseline schema:", err)
+	}
+
+	l, err := lockFile(lockPath(file), 0)
+	if err != nil {
+		t.Fatal("Failed to take the lock:", err)
+	}
+	viper.Set(vkeys.LockTimeout, 100*time.Millisecond)
+	defer viper.Set(vkeys.LockTimeout, nil)
+	if _, err := Open(BackendSqlite, file); !errors.Is(err, ErrLocked) {
+		t.Fatal("Expected ErrLocked, found:", err)
+	}
+	if backups, _ := ListBackups(file); len(backups) != 0 {
+		t.Fatal("Backup is created while locked:", len(backups))
+	}
+	l.Unlock()
+
+	b, err := Open(BackendSqlite, file)
+	if err != nil {
+		t.Fatal("Failed to migrate database:", err)
+	}
+	b.Close()
+}
+
+func TestJSONConcurrentWriters(t *testing.T) {
+	id := newTestIdentity(t)
+	b, file := newTestBackend(t, BackendJSON, id)
+	if err := b.Flush(); err != nil {
+		t.Fatal("Failed to flush:", err)
+	}
+	viper.Set(vkeys.LockTimeout, 100*time.Millisecond)
+	defer viper.Set(vkeys.LockTimeout, nil)
+
+	// Loaded databases don't block other processes
+	b1, err := Open(BackendJSON, file)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	b2, err := Open(BackendJSON, file)
+	if err != nil {
+		t.Fatal("Failed to open backend while it's open:", err)
+	}
+	i1, err := b1.CreateItem(newPasswordItem("a", "a-secret"))
+	if err != nil {
+		t.Fatal("Failed to create item:", err)
+	}
+	i2, err := b2.CreateItem(newPasswordItem("b", "b-secret"))
+	if err != nil {
+		t.Fatal("Failed to create item:", err)
+	}
+	if i1.ID != i2.ID {
+		t.Fatal("Expected both to take the same id, found", i1.ID, i2.ID)
+	}
+	if err := b1.Flush(); err != nil {
+		t.Fatal("Failed to flush:", err)
+	}
+	// b2 is applied again on top of b1
+	if err := b2.Flush(); err != nil {
+		t.Fatal("Failed to flush:", err)
+	}
+	if i1.ID == i2.ID {
+		t.Fatal("Item of the later writer kept the taken id", i2.ID)
+	}
+
+	b, err = Open(BackendJSON, file)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	defer b.Close()
+	items, err := b.ListAllItems()
+	checkItems(t, items, err, "a", "b")
+}
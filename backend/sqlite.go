@@ -2,6 +2,7 @@ package backend
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/riadafridishibly/mypass/config"
@@ -12,32 +13,100 @@ import (
 )
 
 type SqliteBackend struct {
+	file   string
 	engine *xorm.Engine
 }
 
+// transaction runs f in a transaction holding the database lock, so
+// writes of concurrent processes are serialized.
+func (b *SqliteBackend) transaction(f func(s *xorm.Session) (any, error)) (any, error) {
+	l, err := lockFile(lockPath(b.file), viper.GetDuration(vkeys.LockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+	return b.engine.Transaction(f)
+}
+
 // AddPublicKeys implements Backend
 func (b *SqliteBackend) AddPublicKeys(pubKeys ...string) error {
 	if len(pubKeys) == 0 {
 		return nil
 	}
-	var v []PublicKey
-	for _, pubKey := range pubKeys {
-		v = append(v, PublicKey{Key: pubKey})
-	}
-	affected, err := b.engine.Insert(&v)
+	current, err := b.PublicKeys()
 	if err != nil {
 		return err
 	}
-	if affected != int64(len(pubKeys)) {
-		// Some are not inserted!
-		return errors.New("some keys are not inserted")
+	keys, err := addKeys(current, pubKeys...)
+	if err != nil {
+		return err
 	}
-	return nil
+	return b.updatePublicKeys(keys, func(s *xorm.Session) error {
+		var v []PublicKey
+		for _, pubKey := range pubKeys {
+			v = append(v, PublicKey{Key: pubKey})
+		}
+		affected, err := s.Insert(&v)
+		if err != nil {
+			return err
+		}
+		if affected != int64(len(pubKeys)) {
+			// Some are not inserted!
+			return errors.New("some keys are not inserted")
+		}
+		return nil
+	})
 }
 
 // RemovePublicKeys implements Backend
-func (*SqliteBackend) RemovePublicKeys(pubKeys ...string) error {
-	panic("unimplemented")
+func (b *SqliteBackend) RemovePublicKeys(pubKeys ...string) error {
+	if len(pubKeys) == 0 {
+		return nil
+	}
+	current, err := b.PublicKeys()
+	if err != nil {
+		return err
+	}
+	keys, err := removeKeys(current, pubKeys...)
+	if err != nil {
+		return err
+	}
+	return b.updatePublicKeys(keys, func(s *xorm.Session) error {
+		for _, pubKey := range pubKeys {
+			_, err := s.Delete(&PublicKey{Key: pubKey})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updatePublicKeys runs modify and re-encrypts every item for keys in a
+// single transaction, so either all items are readable by the new set of
+// keys or nothing is changed.
+func (b *SqliteBackend) updatePublicKeys(keys []string, modify func(s *xorm.Session) error) error {
+	restore := setPublicKeys(keys)
+	_, err := b.transaction(func(s *xorm.Session) (any, error) {
+		if err := modify(s); err != nil {
+			return nil, err
+		}
+		var items []*models.Item
+		if err := s.Find(&items); err != nil {
+			return nil, err
+		}
+		for _, i := range items {
+			// ToDB of the inner items encrypts again with the new keys
+			if _, err := s.ID(i.ID).AllCols().Update(i); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		restore()
+	}
+	return err
 }
 
 type PublicKey struct {
@@ -71,15 +140,24 @@ func newSqliteBackend() (Backend, error) {
 
 // CreateItem implements Backend
 func (b *SqliteBackend) CreateItem(i *models.Item) (*models.Item, error) {
-	val, err := b.engine.Transaction(func(s *xorm.Session) (any, error) {
-		affected, err := b.engine.Insert(i)
+	i.Meta.SetDefaults()
+	val, err := b.transaction(func(s *xorm.Session) (any, error) {
+		affected, err := s.Insert(i)
 		if err != nil {
 			return nil, err
 		}
 		if affected == 0 {
 			return nil, errors.New("failed to insert data")
 		}
-		return b.GetItemByID(i.ID)
+		var created models.Item
+		found, err := s.ID(i.ID).Get(&created)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: id=%d", models.ErrItemNotFound, i.ID)
+		}
+		return &created, nil
 	})
 	if err != nil {
 		return nil, err
@@ -92,6 +170,11 @@ func (b *SqliteBackend) Flush() error {
 	return b.engine.Close()
 }
 
+// Close implements Backend
+func (b *SqliteBackend) Close() error {
+	return b.engine.Close()
+}
+
 // GetItemByID implements Backend
 func (b *SqliteBackend) GetItemByID(id int) (*models.Item, error) {
 	var i models.Item
@@ -101,7 +184,7 @@ func (b *SqliteBackend) GetItemByID(id int) (*models.Item, error) {
 		return nil, err
 	}
 	if !found {
-		return nil, errors.New("item not found")
+		return nil, fmt.Errorf("%w: id=%d", models.ErrItemNotFound, id)
 	}
 	return &i, nil
 }
@@ -113,8 +196,13 @@ func (b *SqliteBackend) Init(cfg *config.Config) error {
 	if err != nil {
 		return err
 	}
+	b.file = cfg.DatabasePath
 	b.engine = e
-	return b.engine.Sync(new(models.Item), new(PublicKey))
+	if err := b.migrate(); err != nil {
+		b.engine.Close()
+		return err
+	}
+	return nil
 }
 
 // ListAllItems implements Backend
@@ -125,13 +213,71 @@ func (b *SqliteBackend) ListAllItems() ([]*models.Item, error) {
 }
 
 // RemoveItemByID implements Backend
-func (*SqliteBackend) RemoveItemByID(id int) (*models.Item, error) {
-	panic("unimplemented")
+func (b *SqliteBackend) RemoveItemByID(id int) (*models.Item, error) {
+	val, err := b.transaction(func(s *xorm.Session) (any, error) {
+		var i models.Item
+		found, err := s.ID(id).Get(&i)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: id=%d", models.ErrItemNotFound, id)
+		}
+		affected, err := s.ID(id).Delete(new(models.Item))
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, errors.New("failed to remove data")
+		}
+		return &i, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.Item), nil
 }
 
 // UpdateItemByID implements Backend
-func (*SqliteBackend) UpdateItemByID(id int, i *models.Item) (*models.Item, error) {
-	panic("unimplemented")
+func (b *SqliteBackend) UpdateItemByID(id int, p *models.ItemPatch) (*models.Item, error) {
+	return b.modifyItem(id, p.Apply)
+}
+
+// TrashItemByID implements Backend
+func (b *SqliteBackend) TrashItemByID(id int) (*models.Item, error) {
+	return b.modifyItem(id, (*models.Item).Trash)
+}
+
+// RestoreItemByID implements Backend
+func (b *SqliteBackend) RestoreItemByID(id int) (*models.Item, error) {
+	return b.modifyItem(id, (*models.Item).Restore)
+}
+
+// modifyItem loads the item, modifies it and writes it back in one
+// transaction.
+func (b *SqliteBackend) modifyItem(id int, modify func(i *models.Item) error) (*models.Item, error) {
+	val, err := b.transaction(func(s *xorm.Session) (any, error) {
+		var i models.Item
+		found, err := s.ID(id).Get(&i)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: id=%d", models.ErrItemNotFound, id)
+		}
+		if err := modify(&i); err != nil {
+			return nil, err
+		}
+		_, err = s.ID(id).AllCols().Update(&i)
+		if err != nil {
+			return nil, err
+		}
+		return &i, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.(*models.Item), nil
 }
 
 var _ Backend = (*SqliteBackend)(nil)
@@ -0,0 +1,193 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+	"xorm.io/xorm"
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of mypass")
+
+// SchemaVersion records every migration applied to the sqlite database.
+// Databases created before versioning have no rows, that is version 0.
+type SchemaVersion struct {
+	Version     int       `xorm:"pk 'version'"`
+	Description string    `xorm:"'description'"`
+	AppliedAt   time.Time `xorm:"'applied_at'"`
+}
+
+type sqliteMigration struct {
+	Version     int
+	Description string
+	Up          func(s *xorm.Session) error
+}
+
+// sqliteMigrations must be ordered by version, never change or remove
+// a released migration, add a new one instead.
+var sqliteMigrations = []sqliteMigration{
+	{
+		Version:     1,
+		Description: "persist item timestamps",
+		Up: func(s *xorm.Session) error {
+			for _, col := range []string{"created_at", "updated_at"} {
+				_, err := s.Exec(fmt.Sprintf("ALTER TABLE `item` ADD COLUMN `%s` DATETIME NULL", col))
+				if err != nil {
+					return err
+				}
+			}
+			// Timestamps of older items are unknown, the migration time is
+			// the best we have
+			now := time.Now()
+			_, err := s.Exec("UPDATE `item` SET `created_at` = ?, `updated_at` = ?", now, now)
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "add password history",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE `item` ADD COLUMN `history` TEXT NULL")
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "add trash",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE `item` ADD COLUMN `deleted_at` DATETIME NULL")
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "add totp items",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE `item` ADD COLUMN `totp` TEXT NULL")
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "add note items",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE `item` ADD COLUMN `note` TEXT NULL")
+			return err
+		},
+	},
+	{
+		Version:     6,
+		Description: "add card items",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE `item` ADD COLUMN `card` TEXT NULL")
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "add api key items",
+		Up: func(s *xorm.Session) error {
+			_, err := s.Exec("ALTER TABLE `item` ADD COLUMN `api_key` TEXT NULL")
+			return err
+		},
+	},
+}
+
+func latestSchemaVersion() int {
+	return sqliteMigrations[len(sqliteMigrations)-1].Version
+}
+
+func (b *SqliteBackend) schemaVersion() (int, error) {
+	var v SchemaVersion
+	found, err := b.engine.Desc("version").Get(&v)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, nil
+	}
+	return v.Version, nil
+}
+
+// migrate brings the database schema to the latest version. A new database
+// gets the latest schema directly, an older one is backed up and upgraded
+// by the pending migrations, each in its own transaction.
+func (b *SqliteBackend) migrate() error {
+	// An up to date database is only read, don't wait for the lock
+	if current, err := b.schemaVersion(); err == nil && current == latestSchemaVersion() {
+		return nil
+	}
+	// Another process may be migrating or writing, the version is read
+	// again under the lock and held until every step is done
+	l, err := lockFile(lockPath(b.file), viper.GetDuration(vkeys.LockTimeout))
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+	return b.migrateLocked()
+}
+
+func (b *SqliteBackend) migrateLocked() error {
+	if err := b.engine.Sync(new(SchemaVersion)); err != nil {
+		return err
+	}
+	exists, err := b.engine.IsTableExist(new(models.Item))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := b.engine.Sync(new(models.Item), new(PublicKey)); err != nil {
+			return err
+		}
+		_, err := b.engine.Insert(&SchemaVersion{
+			Version:     latestSchemaVersion(),
+			Description: "new database",
+			AppliedAt:   time.Now(),
+		})
+		return err
+	}
+
+	current, err := b.schemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > latestSchemaVersion() {
+		return fmt.Errorf("%w: database has version %d, supported up to %d, please upgrade mypass",
+			ErrSchemaTooNew, current, latestSchemaVersion())
+	}
+	if current == latestSchemaVersion() {
+		return nil
+	}
+
+	// Keep at least one backup, even if backups are disabled
+	keep := viper.GetInt(vkeys.Backups)
+	if keep < 1 {
+		keep = 1
+	}
+	if err := CreateBackup(b.file, keep); err != nil {
+		return err
+	}
+	for _, m := range sqliteMigrations {
+		if m.Version <= current {
+			continue
+		}
+		_, err := b.engine.Transaction(func(s *xorm.Session) (any, error) {
+			if err := m.Up(s); err != nil {
+				return nil, err
+			}
+			return s.Insert(&SchemaVersion{
+				Version:     m.Version,
+				Description: m.Description,
+				AppliedAt:   time.Now(),
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate database to version %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/riadafridishibly/mypass/models"
+	"xorm.io/xorm"
+)
+
+// baselineItem is the item table before the schema was versioned.
+type baselineItem struct {
+	ID        int     `xorm:"pk autoincr 'id'"`
+	Title     string  `xorm:"'title'"`
+	Namespace string  `xorm:"'namespace'"`
+	Type      string  `xorm:"'type'"`
+	Password  string  `xorm:"text 'password'"`
+	SSH       *string `xorm:"text 'ssh'"`
+}
+
+func (baselineItem) TableName() string {
+	return "item"
+}
+
+func newTestEngine(t *testing.T, file string) *xorm.Engine {
+	t.Helper()
+	e, err := xorm.NewEngine("sqlite3", file)
+	if err != nil {
+		t.Fatal("Failed to open sqlite database:", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestMigrateBaselineSchema(t *testing.T) {
+	id := newTestIdentity(t)
+	useIdentities(t, id)
+	file := filepath.Join(t.TempDir(), "db")
+	e := newTestEngine(t, file)
+	if err := e.Sync(new(baselineItem), new(PublicKey)); err != nil {
+		t.Fatal("Failed to create baseline schema:", err)
+	}
+	password, err := (&models.PasswordItem{Username: "me", SiteName: "a", Password: "a-secret"}).ToDB()
+	if err != nil {
+		t.Fatal("Failed to encrypt item:", err)
+	}
+	_, err = e.Insert(
+		&PublicKey{Key: id.Recipient().String()},
+		&baselineItem{Title: "a", Namespace: "default", Type: string(models.ItemPassword), Password: string(password)},
+	)
+	if err != nil {
+		t.Fatal("Failed to insert baseline rows:", err)
+	}
+
+	start := time.Now().Truncate(time.Second)
+	b, err := Open(BackendSqlite, file)
+	if err != nil {
+		t.Fatal("Failed to migrate baseline database:", err)
+	}
+	defer b.Close()
+	v, err := b.(*SqliteBackend).schemaVersion()
+	if err != nil {
+		t.Fatal("Failed to read schema version:", err)
+	}
+	if v != latestSchemaVersion() {
+		t.Fatalf("Expected schema version %d, found %d", latestSchemaVersion(), v)
+	}
+	items, err := b.ListAllItems()
+	checkItems(t, items, err, "a")
+	// Unknown timestamps are set to the migration time
+	if m := items[0].Meta; m.CreatedAt.Before(start) || m.UpdatedAt.Before(start) {
+		t.Fatal("Timestamps aren't backfilled:", m.CreatedAt, m.UpdatedAt)
+	}
+	// Items using the new columns can be stored
+	note := &models.Item{Title: "n", Namespace: "default", Type: models.ItemNote, Note: &models.NoteItem{Body: "migrated"}}
+	if _, err := b.CreateItem(note); err != nil {
+		t.Fatal("Failed to create item:", err)
+	}
+	backups, err := ListBackups(file)
+	if err != nil {
+		t.Fatal("Failed to list backups:", err)
+	}
+	if len(backups) != 1 {
+		t.Fatal("Expected a backup of the baseline database, found", len(backups))
+	}
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	b, file := newTestBackend(t, BackendSqlite, newTestIdentity(t))
+	if err := b.Close(); err != nil {
+		t.Fatal("Failed to close backend:", err)
+	}
+	e := newTestEngine(t, file)
+	_, err := e.Insert(&SchemaVersion{
+		Version:     latestSchemaVersion() + 1,
+		Description: "from the future",
+		AppliedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal("Failed to insert schema version:", err)
+	}
+	if _, err := Open(BackendSqlite, file); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatal("Expected ErrSchemaTooNew, found:", err)
+	}
+	backups, err := ListBackups(file)
+	if err != nil {
+		t.Fatal("Failed to list backups:", err)
+	}
+	if len(backups) != 0 {
+		t.Fatal("Backup is created of a newer database:", len(backups))
+	}
+}
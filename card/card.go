@@ -0,0 +1,175 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Visa       = "visa"
+	Mastercard = "mastercard"
+	Amex       = "amex"
+	Discover   = "discover"
+	Diners     = "diners"
+	JCB        = "jcb"
+	UnionPay   = "unionpay"
+	Maestro    = "maestro"
+)
+
+// Normalize removes the spaces and dashes people use to group the digits.
+func Normalize(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(number)
+}
+
+// Luhn reports whether the digits of number have a valid Luhn checksum.
+func Luhn(number string) bool {
+	if number == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+type brandRange struct {
+	brand string
+	// Inclusive range of the leading digits, both have the same length
+	lo, hi string
+}
+
+// Checked in order, the more specific ranges come first
+var brandRanges = []brandRange{
+	{Amex, "34", "34"},
+	{Amex, "37", "37"},
+	{Diners, "300", "305"},
+	{Diners, "36", "36"},
+	{Diners, "38", "39"},
+	{JCB, "3528", "3589"},
+	{Discover, "6011", "6011"},
+	{Discover, "644", "649"},
+	{Discover, "65", "65"},
+	{UnionPay, "62", "62"},
+	{Mastercard, "2221", "2720"},
+	{Mastercard, "51", "55"},
+	{Maestro, "50", "50"},
+	{Maestro, "56", "58"},
+	{Maestro, "6", "6"},
+	{Visa, "4", "4"},
+}
+
+// Brand detects the card network from the leading digits of the
+// normalized number, it's empty if unknown.
+func Brand(number string) string {
+	for _, r := range brandRanges {
+		if len(number) < len(r.lo) {
+			continue
+		}
+		prefix := number[:len(r.lo)]
+		if prefix >= r.lo && prefix <= r.hi {
+			return r.brand
+		}
+	}
+	return ""
+}
+
+// ValidateNumber checks the length and the checksum of the number.
+func ValidateNumber(number string) error {
+	number = Normalize(number)
+	if number == "" {
+		return errors.New("card number can't be empty")
+	}
+	if len(number) < 12 || len(number) > 19 {
+		return fmt.Errorf("card number must have 12 to 19 digits, got %d", len(number))
+	}
+	if !Luhn(number) {
+		return errors.New("invalid card number, checksum doesn't match")
+	}
+	return nil
+}
+
+// Mask hides all but the last four digits of the number.
+func Mask(number string) string {
+	number = Normalize(number)
+	if len(number) <= 4 {
+		return number
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+// ParseExpiry parses MM/YY or MM/YYYY and returns the end of that month,
+// when the card stops being valid.
+func ParseExpiry(s string) (time.Time, error) {
+	m, y, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid expiry %q, expected MM/YY", s)
+	}
+	month, err := strconv.Atoi(strings.TrimSpace(m))
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("invalid expiry month %q", m)
+	}
+	y = strings.TrimSpace(y)
+	year, err := strconv.Atoi(y)
+	if err != nil || (len(y) != 2 && len(y) != 4) {
+		return time.Time{}, fmt.Errorf("invalid expiry year %q", y)
+	}
+	if len(y) == 2 {
+		year += 2000
+	}
+	return time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.Local), nil
+}
+
+// FormatExpiry formats the expiry returned by ParseExpiry as MM/YY.
+func FormatExpiry(t time.Time) string {
+	t = t.AddDate(0, 0, -1)
+	return fmt.Sprintf("%02d/%02d", int(t.Month()), t.Year()%100)
+}
+
+// ValidateCVV checks the security code, amex uses 4 digits.
+func ValidateCVV(cvv, brand string) error {
+	want := 3
+	if brand == Amex {
+		want = 4
+	}
+	if len(cvv) != want || !digits(cvv) {
+		return fmt.Errorf("cvv must have %d digits", want)
+	}
+	return nil
+}
+
+// ValidatePIN checks the pin, it's optional.
+func ValidatePIN(pin string) error {
+	if pin == "" {
+		return nil
+	}
+	if len(pin) < 4 || len(pin) > 12 || !digits(pin) {
+		return errors.New("pin must have 4 to 12 digits")
+	}
+	return nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
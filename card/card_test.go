@@ -0,0 +1,74 @@
+package card
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateNumber(t *testing.T) {
+	for _, number := range []string{
+		"4242 4242 4242 4242",
+		"5555-5555-5555-4444",
+		"378282246310005",
+		"6011111111111117",
+	} {
+		if err := ValidateNumber(number); err != nil {
+			t.Errorf("%s: %v", number, err)
+		}
+	}
+	for _, number := range []string{
+		"",
+		"4242424242424241",
+		"42424242",
+		"4242a24242424242",
+	} {
+		if err := ValidateNumber(number); err == nil {
+			t.Errorf("%s: expected error", number)
+		}
+	}
+}
+
+func TestBrand(t *testing.T) {
+	tests := map[string]string{
+		"4242424242424242": Visa,
+		"5555555555554444": Mastercard,
+		"2223003122003222": Mastercard,
+		"378282246310005":  Amex,
+		"6011111111111117": Discover,
+		"36227206271667":   Diners,
+		"3566002020360505": JCB,
+		"6200000000000005": UnionPay,
+		"9999999999999995": "",
+	}
+	for number, want := range tests {
+		if got := Brand(number); got != want {
+			t.Errorf("%s: got %q, want %q", number, got, want)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	if got := Mask("4242 4242 4242 4242"); got != "************4242" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	for _, s := range []string{"02/27", "2/2027", " 02 / 27 "} {
+		got, err := ParseExpiry(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Date(2027, 3, 1, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+			t.Errorf("%s: got %s, want %s", s, got, want)
+		}
+		if FormatExpiry(got) != "02/27" {
+			t.Errorf("%s: formatted as %s", s, FormatExpiry(got))
+		}
+	}
+	for _, s := range []string{"", "13/27", "02-27", "02/123"} {
+		if _, err := ParseExpiry(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
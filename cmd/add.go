@@ -22,15 +22,100 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/riadafridishibly/mypass/backend"
 	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// passwordFromFlags returns the password given with --password or
+// --password-stdin of the add subcommand with the viper key prefix.
+func passwordFromFlags(prefix string) (string, error) {
+	return secretFromFlags(prefix, "password")
+}
+
+// secretFromFlags returns the secret given with --<name> or --<name>-stdin
+// of the add subcommand with the viper key prefix.
+func secretFromFlags(prefix, name string) (string, error) {
+	if !viper.GetBool(prefix + "." + name + "-stdin") {
+		return viper.GetString(prefix + "." + name), nil
+	}
+	if viper.GetString(prefix+"."+name) != "" {
+		return "", fmt.Errorf("--%s and --%s-stdin can't be used together", name, name)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read %s from stdin: %w", name, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// needPrompt reports whether the interactive editor should be opened,
+// it fails if that's required but not possible.
+func needPrompt(prefix string, missing []string) (bool, error) {
+	if !viper.GetBool("add.interactive") && len(missing) == 0 {
+		return false, nil
+	}
+	stdinUsed := false
+	for _, name := range []string{"password", "secret", "passphrase"} {
+		stdinUsed = stdinUsed || viper.GetBool(prefix+"."+name+"-stdin")
+	}
+	if stdinUsed || !stdinIsTerminal() {
+		if len(missing) > 0 {
+			return false, fmt.Errorf("missing required flags: %s", strings.Join(missing, ", "))
+		}
+		return false, errors.New("interactive mode requires a terminal")
+	}
+	return true, nil
+}
+
+// ensurePassword asks for a password if it's empty and we have a terminal.
+func ensurePassword(pass string) (string, error) {
+	if pass != "" {
+		return pass, nil
+	}
+	if !stdinIsTerminal() {
+		return "", errors.New("password is required, use --password-stdin")
+	}
+	return readOrGeneratePassword()
+}
+
+func createItem(i *models.Item) error {
+	a, err := backend.Get()
+	if err != nil {
+		return err
+	}
+	created, err := a.CreateItem(i)
+	if err != nil {
+		return err
+	}
+	if err := a.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("Added %s\n", created)
+	return nil
+}
+
 // addCmd represents the add command
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add new items to the database",
+	Long: `Add new items to the database.
+
+Items are created from the flags. The interactive editor is opened when
+--interactive is given or when a required flag is missing.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		if fn := rootCmd.PersistentPreRunE; fn != nil {
 			err := fn(cmd, args)
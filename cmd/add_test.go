@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+func TestAddWithoutTerminal(t *testing.T) {
+	v := newTestVault(t)
+	args := []string{"add", "pass", "--username", "me", "--site", "example.com", "--password-stdin"}
+	_, err := v.run(t, "s3cret\n", args...)
+	if err == nil || !strings.Contains(err.Error(), "PASSWORD") {
+		t.Fatal("Expected an error about the master password, found:", err)
+	}
+	if items := v.items(t); len(items) != 0 {
+		t.Fatal("Item is added without the master password:", len(items))
+	}
+
+	// As set from the PASSWORD environment variable
+	viper.Set(vkeys.Password, "test")
+	if _, err := v.run(t, "s3cret\n", args...); err != nil {
+		t.Fatal("Failed to add item:", err)
+	}
+	items := v.items(t)
+	if len(items) != 1 || items[0].Password.Password != "s3cret" {
+		t.Fatal("Unexpected items:", items)
+	}
+}
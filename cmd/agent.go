@@ -0,0 +1,229 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/manifoldco/promptui"
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/sshagent"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// vaultMu serializes readVault, the agent serves every connection in its
+// own goroutine but the decryption flag is global and viper isn't safe for
+// concurrent use.
+var vaultMu sync.Mutex
+
+// readVault opens the database, runs f and releases it without writing,
+// so other processes aren't blocked while the agent runs. The secrets are
+// decrypted only if decrypt is set.
+func readVault(decrypt bool, f func(b backend.Backend) error) error {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	viper.Set(vkeys.SkipDecryption, !decrypt)
+	defer viper.Set(vkeys.SkipDecryption, false)
+	b, err := backend.Open(backend.Type(), viper.GetString(vkeys.DatabasePath))
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return f(b)
+}
+
+// vaultKeys returns the keys of the ssh items, only their public keys are
+// read.
+func vaultKeys() ([]*sshagent.Key, error) {
+	var keys []*sshagent.Key
+	err := readVault(false, func(b backend.Backend) error {
+		items, err := b.ListAllItems()
+		if err != nil {
+			return err
+		}
+		for _, i := range models.ActiveItems(items) {
+			if i.SSH == nil {
+				continue
+			}
+			if i.SSH.MissingAuthorizedKey() {
+				// Saved before public keys were stored, it's added once the
+				// item is decrypted and saved again
+				jww.WARN.Printf("ssh item %d (%s/%s) is not offered, its public key is not stored yet. It's stored the next time the item is changed, eg. with edit",
+					i.ID, i.Namespace, i.Title)
+				continue
+			}
+			if i.SSH.AuthorizedKey == "" {
+				continue
+			}
+			pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(i.SSH.AuthorizedKey))
+			if err != nil {
+				jww.ERROR.Printf("item %d has an invalid public key: %v", i.ID, err)
+				continue
+			}
+			id := i.ID
+			keys = append(keys, &sshagent.Key{
+				PublicKey: pub,
+				Comment:   i.Namespace + "/" + i.Title,
+				Load:      func() (any, error) { return vaultPrivateKey(id) },
+			})
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// vaultPrivateKey decrypts the private key of the ssh item.
+func vaultPrivateKey(id int) (any, error) {
+	var key any
+	err := readVault(true, func(b backend.Backend) error {
+		i, err := b.GetItemByID(id)
+		if err != nil {
+			return err
+		}
+		if i.IsTrashed() || i.SSH == nil {
+			return fmt.Errorf("%w: ssh item %d", models.ErrItemNotFound, id)
+		}
+		key, err = i.SSH.ParsePrivateKey()
+		return err
+	})
+	return key, err
+}
+
+// confirmKeyUse asks on the terminal before the key is used.
+func confirmKeyUse(k *sshagent.Key) bool {
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Allow use of %s (%s)", k.Comment, ssh.FingerprintSHA256(k.PublicKey)),
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Serve the vault to other programs",
+}
+
+var agentSSHCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: "Run an ssh agent offering the keys of the ssh items",
+	Long: `Run an ssh agent offering the keys of the ssh items.
+
+The agent listens on a unix socket until it's interrupted. It offers the
+keys of the ssh items from the database as it's at the time of the request,
+a private key is decrypted only when a signature is requested. Keys can't
+be added or removed through the agent, use add ssh, edit and remove.
+
+Point ssh to the agent with the printed SSH_AUTH_SOCK.`,
+	Example: `  mypass agent ssh
+  mypass agent ssh --socket ~/.mypass-agent.sock --confirm`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipBackend: "true"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var confirm func(k *sshagent.Key) bool
+		if viper.GetBool("agent.confirm") {
+			if !stdinIsTerminal() {
+				return errors.New("--confirm requires a terminal")
+			}
+			confirm = confirmKeyUse
+		}
+		keys, err := vaultKeys()
+		if err != nil {
+			return err
+		}
+
+		sock := viper.GetString("agent.socket")
+		if sock == "" {
+			dir, err := privateTempDir()
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			sock = filepath.Join(dir, "agent.sock")
+		}
+		l, err := net.Listen("unix", sock)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		if err := os.Chmod(sock, 0600); err != nil {
+			return err
+		}
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+		go func() {
+			<-sigs
+			l.Close()
+		}()
+
+		fmt.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", sock)
+		fmt.Fprintf(os.Stderr, "Agent is running with %d key(s), press Ctrl+C to stop.\n", len(keys))
+		ag := sshagent.New(vaultKeys, confirm)
+		for {
+			conn, err := l.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			go func() {
+				defer conn.Close()
+				if err := agent.ServeAgent(ag, conn); err != nil && !errors.Is(err, io.EOF) {
+					jww.ERROR.Println("agent:", err)
+				}
+			}()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentSSHCmd)
+
+	agentSSHCmd.Flags().StringP("socket", "a", "", "Listen on this unix socket (default is a private temporary directory)")
+	viper.BindPFlag("agent.socket", agentSSHCmd.Flags().Lookup("socket"))
+
+	agentSSHCmd.Flags().BoolP("confirm", "c", false, "Ask on the terminal before each use of a key")
+	viper.BindPFlag("agent.confirm", agentSSHCmd.Flags().Lookup("confirm"))
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/sshagent"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestAgentConcurrentRequests(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("Failed to create age x25519 identity:", err)
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal("Failed to marshal private key:", err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "db")
+	viper.Set("backend", backend.BackendJSON)
+	viper.Set(vkeys.DatabasePath, dbPath)
+	viper.Set(vkeys.PrivateKeys, []string{id.String()})
+	viper.Set(vkeys.Password, "test")
+	t.Cleanup(func() {
+		viper.Set("backend", nil)
+		viper.Set(vkeys.DatabasePath, nil)
+		viper.Set(vkeys.PublicKeys, nil)
+		viper.Set(vkeys.PrivateKeys, nil)
+		viper.Set(vkeys.Password, nil)
+	})
+
+	b, err := backend.Open(backend.BackendJSON, dbPath)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	if err := b.AddPublicKeys(id.Recipient().String()); err != nil {
+		t.Fatal("Failed to add public key:", err)
+	}
+	s := &models.SSHItem{Host: "example.com", Username: "me"}
+	if err := s.SetPrivateKey(models.AsymSecretStr(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), ""); err != nil {
+		t.Fatal("Failed to set private key:", err)
+	}
+	_, err = b.CreateItem(&models.Item{Title: "server", Namespace: "default", Type: models.ItemSSH, SSH: s})
+	if err != nil {
+		t.Fatal("Failed to create item:", err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal("Failed to flush:", err)
+	}
+	pub, err := ssh.NewPublicKey(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := sshagent.New(vaultKeys, nil)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for n := 0; n < 10; n++ {
+		server, client := net.Pipe()
+		go agent.ServeAgent(a, server)
+		defer client.Close()
+		defer server.Close()
+		ag := agent.NewClient(client)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			keys, err := ag.List()
+			if err == nil && len(keys) != 1 {
+				t.Error("Expected 1 key, found", len(keys))
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			sig, err := ag.Sign(pub, []byte("data"))
+			if err == nil {
+				err = pub.Verify([]byte("data"), sig)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal("Failed request:", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Manage database backups",
+	Long: `Manage database backups.
+
+The JSON backend creates a backup every time the database is written, the
+sqlite backend before its schema is upgraded. The number of backups kept is
+set with "backups" in the config file (default 10).`,
+	// The database must not be open while it's restored
+	Annotations: map[string]string{skipBackend: "true"},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List backups, newest first",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipBackend: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backups, err := backend.ListBackups(viper.GetString(vkeys.DatabasePath))
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "N\tCREATED\tSIZE\tPATH")
+		for idx, b := range backups {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", idx+1, b.CreatedAt.Format(time.RFC3339), b.Size, b.Path)
+		}
+		return w.Flush()
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <n>",
+	Short: "Replace the database with the n-th backup from backup list",
+	Long: `Replace the database with the n-th backup from backup list.
+
+The current database is backed up first, so the restore can be undone.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipBackend: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath := viper.GetString(vkeys.DatabasePath)
+		backups, err := backend.ListBackups(dbPath)
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(backups) {
+			return fmt.Errorf("invalid backup %q, there are %d backups", args[0], len(backups))
+		}
+		b := backups[n-1]
+		if !viper.GetBool("backup.restore.yes") {
+			err := confirm(fmt.Sprintf("Replace %s with the backup from %s", dbPath, b.CreatedAt.Format(time.RFC3339)))
+			if errors.Is(err, promptui.ErrAbort) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := backend.RestoreBackup(dbPath, b, viper.GetInt(vkeys.Backups)); err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s\n", b.Path)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupListCmd, backupRestoreCmd)
+
+	backupRestoreCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	viper.BindPFlag("backup.restore.yes", backupRestoreCmd.Flags().Lookup("yes"))
+}
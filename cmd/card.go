@@ -0,0 +1,165 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/riadafridishibly/mypass/card"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newDefaultCardItem() *models.Item {
+	return &models.Item{
+		Title:     "",
+		Namespace: "default",
+		Type:      models.ItemCard,
+		Meta: models.Meta{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		Card: &models.CardItem{},
+	}
+}
+
+func newCardFieldsWithConfig(i *models.Item) FieldsWithConfig {
+	return FieldsWithConfig{
+		"Title": &FieldConfig{
+			Default:    i.Title,
+			ValidateFn: func(string) error { return nil },
+		},
+		"Namespace": &FieldConfig{
+			Default: i.Namespace,
+			ValidateFn: func(s string) error {
+				if s == "" {
+					return errors.New("namespace can't be empty")
+				}
+				return nil
+			},
+		},
+		"Cardholder": &FieldConfig{
+			Default: i.Card.Cardholder,
+			ValidateFn: func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return errors.New("cardholder can't be empty")
+				}
+				return nil
+			},
+		},
+		"Number": &FieldConfig{
+			Mask:       true,
+			Default:    string(i.Card.Number),
+			ValidateFn: card.ValidateNumber,
+		},
+		"Expiry": &FieldConfig{
+			Default: i.Card.Expiry,
+			ValidateFn: func(s string) error {
+				_, err := card.ParseExpiry(s)
+				return err
+			},
+		},
+		"CVV": &FieldConfig{
+			Mask:    true,
+			Default: string(i.Card.CVV),
+			ValidateFn: func(s string) error {
+				if s == "" {
+					return nil
+				}
+				// Amex uses 4 digits, the length is checked against the brand on save
+				if card.ValidateCVV(s, card.Visa) != nil && card.ValidateCVV(s, card.Amex) != nil {
+					return errors.New("cvv must have 3 or 4 digits")
+				}
+				return nil
+			},
+		},
+		"PIN": &FieldConfig{
+			Mask:       true,
+			Default:    string(i.Card.PIN),
+			ValidateFn: card.ValidatePIN,
+		},
+	}
+}
+
+const cardDetailsTpl = `
+--------- Payment Card ----------
+{{ "Title:" | faint }}	{{ .Value.Title }}
+{{ "Namespace:" | faint }}	{{ .Value.Namespace }}
+{{ "Cardholder:" | faint }}	{{ .Value.Cardholder }}
+{{ "Expiry:" | faint }}	{{ .Value.Expiry }}`
+
+// cardCmd represents the add card command
+var cardCmd = &cobra.Command{
+	Use:   "card",
+	Short: "Add payment card",
+	Long: `Add payment card.
+
+The number, CVV and PIN are entered in the interactive editor, the number is
+checked with the Luhn algorithm and the brand is detected from it.`,
+	SilenceUsage: true,
+	Example:      `  mypass add card --cardholder "ACME Corp" --expiry 12/27`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !stdinIsTerminal() {
+			return errors.New("adding cards requires a terminal")
+		}
+		i := newDefaultCardItem()
+		i.Namespace = viper.GetString("add.namespace")
+		i.Title = viper.GetString("add.title")
+		i.Card.Cardholder = viper.GetString("card.cardholder")
+		i.Card.Expiry = viper.GetString("card.expiry")
+
+		v, err := Prompt(newCardFieldsWithConfig(i), cardDetailsTpl)
+		if err != nil {
+			return err
+		}
+		i.Title = v["Title"]
+		i.Namespace = v["Namespace"]
+		i.Card.Cardholder = strings.TrimSpace(v["Cardholder"])
+		i.Card.Number = models.AsymSecretStr(v["Number"])
+		i.Card.Expiry = v["Expiry"]
+		i.Card.CVV = models.AsymSecretStr(v["CVV"])
+		i.Card.PIN = models.AsymSecretStr(v["PIN"])
+		if err := i.Card.Normalize(); err != nil {
+			return err
+		}
+		if i.Title == "" {
+			number := string(i.Card.Number)
+			i.Title = fmt.Sprintf("%s %s", i.Card.Brand, number[len(number)-4:])
+			i.Title = strings.TrimSpace(i.Title)
+		}
+		return createItem(i)
+	},
+}
+
+func init() {
+	addCmd.AddCommand(cardCmd)
+
+	cardCmd.Flags().String("cardholder", "", "Name on the card")
+	viper.BindPFlag("card.cardholder", cardCmd.Flags().Lookup("cardholder"))
+
+	cardCmd.Flags().String("expiry", "", "Expiry date, MM/YY")
+	viper.BindPFlag("card.expiry", cardCmd.Flags().Lookup("expiry"))
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+	"golang.design/x/clipboard"
+)
+
+// copyToClipboard initializes the clipboard on first use, so commands
+// which don't copy anything work without a display. The data is cleared
+// from the clipboard after the configured timeout.
+func copyToClipboard(data []byte) error {
+	err := clipboard.Init()
+	if err != nil {
+		return err
+	}
+	clipboard.Write(clipboard.FmtText, data)
+	timeout := viper.GetDuration(vkeys.ClipboardTimeout)
+	if timeout <= 0 {
+		return nil
+	}
+	if err := scheduleClipboardClear(data, timeout); err != nil {
+		jww.ERROR.Println("failed to schedule clipboard clear:", err)
+		return nil
+	}
+	return nil
+}
+
+func clipboardDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// scheduleClipboardClear starts a detached clipboard-clear process, so we
+// can exit while the clipboard still gets cleared. Only the digest of the
+// copied data is passed, through stdin.
+func scheduleClipboardClear(data []byte, after time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(exe, clipboardClearCmd.Name(), "--after", after.String())
+	detach(c)
+	if err := startWithInput(c, clipboardDigest(data)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Clipboard will be cleared in %s.\n", after)
+	return c.Process.Release()
+}
+
+// startWithInput starts c with input on its stdin. The input is in the
+// pipe when it returns, exec would copy it from a goroutine which may not
+// run before we exit.
+func startWithInput(c *exec.Cmd, input string) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	c.Stdin = r
+	if err := c.Start(); err != nil {
+		w.Close()
+		return err
+	}
+	// Smaller than the pipe buffer, it doesn't block
+	_, err = io.WriteString(w, input)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+var clipboardClearCmd = &cobra.Command{
+	Use:          "clipboard-clear",
+	Short:        "Clear the clipboard if it still holds the copied data",
+	Hidden:       true,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipBackend: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		digest, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := clipboard.Init(); err != nil {
+			return err
+		}
+		ours := func(data []byte) bool {
+			return clipboardDigest(data) == string(bytes.TrimSpace(digest))
+		}
+		after, _ := cmd.Flags().GetDuration("after")
+		ctx, cancel := context.WithTimeout(context.Background(), after)
+		defer cancel()
+		// Nothing to do if something else is copied in the meantime
+		for data := range clipboard.Watch(ctx, clipboard.FmtText) {
+			if !ours(data) {
+				return nil
+			}
+		}
+		if ours(clipboard.Read(clipboard.FmtText)) {
+			clipboard.Write(clipboard.FmtText, []byte{})
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clipboardClearCmd)
+
+	clipboardClearCmd.Flags().Duration("after", 45*time.Second, "Clear after this duration")
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStartWithInput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	digest := clipboardDigest([]byte("secret"))
+	c := exec.Command("sh", "-c", `sleep 0.2; cat > "$0.tmp" && mv "$0.tmp" "$0"`, out)
+	if err := startWithInput(c, digest); err != nil {
+		t.Fatal("Failed to start:", err)
+	}
+	// Not waited for, like the detached clipboard-clear
+	if err := c.Process.Release(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(out)
+		if err == nil {
+			if string(data) != digest {
+				t.Fatalf("Expected digest %q, found %q", digest, data)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Helper didn't get the digest:", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// The askpass helper finds the password server with this variable
+const askpassSocketEnv = "MYPASS_ASKPASS_SOCKET"
+
+// ssh asks this many times before it gives up, see NumberOfPasswordPrompts
+const askpassMaxAnswers = 3
+
+// sshArgs returns the arguments of ssh to connect to the item's host,
+// extra arguments come after the destination, ssh parses the options
+// there too.
+func sshArgs(s *models.SSHItem, extra []string) []string {
+	var args []string
+	if s.Port != 0 {
+		args = append(args, "-p", strconv.FormatUint(uint64(s.Port), 10))
+	}
+	dest := s.Host
+	if s.Username != "" {
+		dest = s.Username + "@" + s.Host
+	}
+	return append(append(args, dest), extra...)
+}
+
+// askpassServer serves the password to the askpass helper through a unix
+// socket in a private directory, so it's never in the environment or the
+// arguments of a process.
+type askpassServer struct {
+	dir string
+	l   net.Listener
+}
+
+func newAskpassServer(password string) (*askpassServer, error) {
+	dir, err := privateTempDir()
+	if err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", filepath.Join(dir, "askpass.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	go func() {
+		for n := 0; n < askpassMaxAnswers; n++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			io.WriteString(conn, password)
+			conn.Close()
+		}
+		// Out of answers, the password is wrong
+		l.Close()
+	}()
+	return &askpassServer{dir: dir, l: l}, nil
+}
+
+// env returns the environment variables which make ssh use the helper.
+func (s *askpassServer) env() ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	// SSH_ASKPASS takes a program without arguments
+	script := filepath.Join(s.dir, "askpass")
+	content := "#!/bin/sh\nexec '" + strings.ReplaceAll(exe, "'", `'\''`) + "' " + askpassCmd.Name() + " \"$@\"\n"
+	if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+		return nil, err
+	}
+	return []string{
+		"SSH_ASKPASS=" + script,
+		"SSH_ASKPASS_REQUIRE=force",
+		askpassSocketEnv + "=" + s.l.Addr().String(),
+	}, nil
+}
+
+func (s *askpassServer) Close() error {
+	s.l.Close()
+	return os.RemoveAll(s.dir)
+}
+
+// runSSH runs ssh in the foreground, interrupts are handled by ssh.
+func runSSH(args []string, env []string) error {
+	path, err := exec.LookPath("ssh")
+	if err != nil {
+		return err
+	}
+	c := exec.Command(path, args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), env...)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+	jww.INFO.Println("running:", path, strings.Join(args, " "))
+	return c.Run()
+}
+
+// connectCmd represents the connect command
+var connectCmd = &cobra.Command{
+	Use:   "connect <id|title|namespace/title> [-- ssh arguments...]",
+	Short: "Open a ssh session to the host of a ssh item",
+	Long: `Open a ssh session to the host of a ssh item with the system ssh.
+
+The private key of the item is loaded into the ssh agent first, for the
+given lifetime. The password is answered through a temporary SSH_ASKPASS
+helper, other questions of ssh like host key confirmations are asked on
+the terminal.
+
+Arguments after -- are passed to ssh, after the destination.`,
+	Example: `  mypass connect prod-server
+  mypass connect prod-server -- -L 8080:localhost:80
+  mypass connect prod-server -- uptime`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := findItem(a, args[0])
+		if err != nil {
+			return err
+		}
+		if i.SSH == nil {
+			return fmt.Errorf("item %d is not a ssh item", i.ID)
+		}
+		if i.SSH.HasKey() {
+			lifetime, err := parseDuration(viper.GetString("connect.lifetime"))
+			if err != nil {
+				return err
+			}
+			ag, conn, err := dialAgent()
+			if err != nil {
+				return err
+			}
+			_, err = addToAgent(ag, i, lifetime, false)
+			conn.Close()
+			if err != nil {
+				return err
+			}
+		}
+		var env []string
+		if i.SSH.Password != "" {
+			srv, err := newAskpassServer(string(i.SSH.Password))
+			if err != nil {
+				return err
+			}
+			defer srv.Close()
+			env, err = srv.env()
+			if err != nil {
+				return err
+			}
+		}
+
+		err = runSSH(sshArgs(i.SSH, args[1:]), env)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// ssh already reported it, just exit with its status
+			cmd.SilenceErrors = true
+		}
+		return err
+	},
+}
+
+// askpassCmd is run by ssh through the SSH_ASKPASS script of connect
+var askpassCmd = &cobra.Command{
+	Use:          "askpass [prompt]",
+	Short:        "Answer the questions of ssh for connect",
+	Hidden:       true,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipBackend: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prompt := ""
+		if len(args) > 0 {
+			prompt = args[0]
+		}
+		sock := os.Getenv(askpassSocketEnv)
+		if sock != "" && strings.Contains(strings.ToLower(prompt), "password") {
+			conn, err := net.DialTimeout("unix", sock, 5*time.Second)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			password, err := io.ReadAll(conn)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(password))
+			return nil
+		}
+		return askTerminal(prompt)
+	},
+}
+
+// askTerminal asks the question of ssh on the controlling terminal, ssh
+// reads the answer from stdout.
+func askTerminal(prompt string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("can't ask %q without a terminal: %w", prompt, err)
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	switch os.Getenv("SSH_ASKPASS_PROMPT") {
+	case "none":
+		// Only a notification
+		fmt.Fprintln(tty)
+		return nil
+	case "confirm":
+		line, _ := bufio.NewReader(tty).ReadString('\n')
+		if !strings.EqualFold(strings.TrimSpace(line), "yes") {
+			return errors.New("not confirmed")
+		}
+		return nil
+	}
+	if strings.Contains(prompt, "yes/no") {
+		line, err := bufio.NewReader(tty).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		fmt.Print(line)
+		return nil
+	}
+	answer, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(answer))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(connectCmd)
+	rootCmd.AddCommand(askpassCmd)
+
+	connectCmd.Flags().StringP("lifetime", "t", "1h", "Keep the private key in the ssh agent for this duration, eg. 1h, 1d")
+	viper.BindPFlag("connect.lifetime", connectCmd.Flags().Lookup("lifetime"))
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package cmd
+
+import "os/exec"
+
+// detach is a no-op, the child already outlives us.
+func detach(c *exec.Cmd) {}
@@ -0,0 +1,366 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var editFlags = []string{"title", "namespace", "username", "password", "site", "url", "host", "port", "totp", "key-file"}
+
+func parseItemID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid item id %q", s)
+	}
+	return id, nil
+}
+
+func stringIfChanged(old, cur string) *string {
+	if old == cur {
+		return nil
+	}
+	return &cur
+}
+
+func secretIfChanged(old models.AsymSecretStr, cur string) *models.AsymSecretStr {
+	if string(old) == cur {
+		return nil
+	}
+	v := models.AsymSecretStr(cur)
+	return &v
+}
+
+// editPatchFromPrompt opens the field editor pre-filled with the stored
+// values and returns a patch with the fields that were changed.
+func editPatchFromPrompt(i *models.Item) (*models.ItemPatch, error) {
+	if i.Password != nil {
+		v, err := Prompt(newPassFieldsWithConfig(i), passDetailsTpl)
+		if err != nil {
+			return nil, err
+		}
+		return &models.ItemPatch{
+			Title:     stringIfChanged(i.Title, v["Title"]),
+			Namespace: stringIfChanged(i.Namespace, v["Namespace"]),
+			Password: &models.PasswordItemPatch{
+				Username: stringIfChanged(i.Password.Username, v["Username"]),
+				SiteName: stringIfChanged(i.Password.SiteName, v["SiteName"]),
+				URL:      stringIfChanged(i.Password.URL, v["URL"]),
+				Password: secretIfChanged(i.Password.Password, v["Password"]),
+			},
+		}, nil
+	}
+	if i.SSH != nil {
+		v, err := Prompt(newSSHFieldsWithConfig(i), sshDetailsTpl)
+		if err != nil {
+			return nil, err
+		}
+		p := &models.SSHItemPatch{
+			Host:     stringIfChanged(i.SSH.Host, v["Host"]),
+			Username: stringIfChanged(i.SSH.Username, v["Username"]),
+			Password: secretIfChanged(i.SSH.Password, v["Password"]),
+		}
+		port, err := strconv.ParseUint(v["Port"], 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		if uint16(port) != i.SSH.Port {
+			p.Port = new(uint16)
+			*p.Port = uint16(port)
+		}
+		return &models.ItemPatch{
+			Title:     stringIfChanged(i.Title, v["Title"]),
+			Namespace: stringIfChanged(i.Namespace, v["Namespace"]),
+			SSH:       p,
+		}, nil
+	}
+	if k := i.APIKey; k != nil {
+		v, err := Prompt(newAPIKeyFieldsWithConfig(i), apiKeyDetailsTpl)
+		if err != nil {
+			return nil, err
+		}
+		p := &models.APIKeyItemPatch{
+			Service: stringIfChanged(k.Service, v["Service"]),
+			KeyID:   stringIfChanged(k.KeyID, v["KeyID"]),
+			Secret:  secretIfChanged(k.Secret, v["Secret"]),
+		}
+		if scopes := parseScopes(v["Scopes"]); strings.Join(scopes, ",") != strings.Join(k.Scopes, ",") {
+			p.Scopes = &scopes
+		}
+		if v["Expires"] != formatExpiry(k.ExpiresAt) {
+			expires, err := parseExpiry(v["Expires"])
+			if err != nil {
+				return nil, err
+			}
+			p.ExpiresAt = &expires
+		}
+		return &models.ItemPatch{
+			Title:     stringIfChanged(i.Title, v["Title"]),
+			Namespace: stringIfChanged(i.Namespace, v["Namespace"]),
+			APIKey:    p,
+		}, nil
+	}
+	if i.Card != nil {
+		v, err := Prompt(newCardFieldsWithConfig(i), cardDetailsTpl)
+		if err != nil {
+			return nil, err
+		}
+		return &models.ItemPatch{
+			Title:     stringIfChanged(i.Title, v["Title"]),
+			Namespace: stringIfChanged(i.Namespace, v["Namespace"]),
+			Card: &models.CardItemPatch{
+				Cardholder: stringIfChanged(i.Card.Cardholder, strings.TrimSpace(v["Cardholder"])),
+				Number:     secretIfChanged(i.Card.Number, v["Number"]),
+				Expiry:     stringIfChanged(i.Card.Expiry, v["Expiry"]),
+				CVV:        secretIfChanged(i.Card.CVV, v["CVV"]),
+				PIN:        secretIfChanged(i.Card.PIN, v["PIN"]),
+			},
+		}, nil
+	}
+	if i.Note != nil {
+		body, err := readNoteBody(string(i.Note.Body))
+		if err != nil {
+			return nil, err
+		}
+		return &models.ItemPatch{
+			Note: &models.NoteItemPatch{Body: secretIfChanged(i.Note.Body, body)},
+		}, nil
+	}
+	if i.TOTP != nil {
+		v, err := Prompt(newTOTPFieldsWithConfig(i), totpDetailsTpl)
+		if err != nil {
+			return nil, err
+		}
+		p := &models.ItemPatch{
+			Title:     stringIfChanged(i.Title, v["Title"]),
+			Namespace: stringIfChanged(i.Namespace, v["Namespace"]),
+		}
+		totp := *i.TOTP
+		if v["Secret"] != string(i.TOTP.Secret) {
+			parsed, err := parseTOTP(v["Secret"])
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(strings.TrimSpace(v["Secret"]), "otpauth:") {
+				totp = *parsed
+			} else {
+				totp.Secret = parsed.Secret
+			}
+		}
+		if v["Issuer"] != i.TOTP.Issuer {
+			totp.Issuer = v["Issuer"]
+		}
+		if v["Account"] != i.TOTP.Account {
+			totp.Account = v["Account"]
+		}
+		if totp != *i.TOTP {
+			p.TOTP = &totp
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("item %d has no editable fields", i.ID)
+}
+
+// editPatchFromFlags builds a patch from the flags given on the command line.
+func editPatchFromFlags(cmd *cobra.Command, i *models.Item) (*models.ItemPatch, error) {
+	changed := func(name string) *string {
+		if !cmd.Flags().Changed(name) {
+			return nil
+		}
+		v := viper.GetString("edit." + name)
+		return &v
+	}
+	p := &models.ItemPatch{
+		Title:     changed("title"),
+		Namespace: changed("namespace"),
+	}
+	var password *models.AsymSecretStr
+	if v := changed("password"); v != nil {
+		password = new(models.AsymSecretStr)
+		*password = models.AsymSecretStr(*v)
+	}
+	if cmd.Flags().Changed("totp") {
+		if i.Password == nil && i.TOTP == nil {
+			return nil, fmt.Errorf("flag --totp does not apply to %s items", i.GetType())
+		}
+		// An empty value removes the TOTP
+		p.TOTP = &models.TOTPItem{}
+		if v := viper.GetString("edit.totp"); v != "" {
+			totp, err := parseTOTP(v)
+			if err != nil {
+				return nil, err
+			}
+			p.TOTP = totp
+		}
+	}
+	switch {
+	case i.Password != nil:
+		for _, name := range []string{"host", "port", "key-file"} {
+			if cmd.Flags().Changed(name) {
+				return nil, fmt.Errorf("flag --%s does not apply to password items", name)
+			}
+		}
+		p.Password = &models.PasswordItemPatch{
+			Username: changed("username"),
+			SiteName: changed("site"),
+			URL:      changed("url"),
+			Password: password,
+		}
+	case i.SSH != nil:
+		for _, name := range []string{"site", "url"} {
+			if cmd.Flags().Changed(name) {
+				return nil, fmt.Errorf("flag --%s does not apply to ssh items", name)
+			}
+		}
+		p.SSH = &models.SSHItemPatch{
+			Host:     changed("host"),
+			Username: changed("username"),
+			Password: password,
+		}
+		if cmd.Flags().Changed("port") {
+			p.SSH.Port = new(uint16)
+			*p.SSH.Port = uint16(viper.GetUint("edit.port"))
+		}
+		if file := viper.GetString("edit.key-file"); cmd.Flags().Changed("key-file") && file != "" {
+			key, passphrase, err := readPrivateKey(file, "")
+			if err != nil {
+				return nil, err
+			}
+			p.SSH.PrivateKey, p.SSH.Passphrase = &key, &passphrase
+		} else if cmd.Flags().Changed("key-file") {
+			// An empty value removes the key
+			empty := models.AsymSecretStr("")
+			p.SSH.PrivateKey, p.SSH.Passphrase = &empty, &empty
+		}
+	case i.TOTP != nil, i.Note != nil, i.Card != nil, i.APIKey != nil:
+		for _, name := range []string{"username", "password", "site", "url", "host", "port", "key-file"} {
+			if cmd.Flags().Changed(name) {
+				return nil, fmt.Errorf("flag --%s does not apply to %s items", name, i.GetType())
+			}
+		}
+	}
+	return p, nil
+}
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit <id>",
+	Short: "Edit an existing item",
+	Long: `Edit an existing item.
+
+Without any field flags the interactive editor is opened pre-filled with the
+stored values. If any field flag is given only those fields are updated.
+
+The body of notes is edited in $VISUAL or $EDITOR, or read from stdin if
+it's not a terminal.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseItemID(args[0])
+		if err != nil {
+			return err
+		}
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := activeItemByID(a, id)
+		if err != nil {
+			return err
+		}
+
+		interactive := true
+		for _, name := range editFlags {
+			if cmd.Flags().Changed(name) {
+				interactive = false
+				break
+			}
+		}
+		var p *models.ItemPatch
+		if interactive {
+			p, err = editPatchFromPrompt(i)
+		} else {
+			p, err = editPatchFromFlags(cmd, i)
+		}
+		if err != nil {
+			return err
+		}
+		if p.IsEmpty() {
+			fmt.Println("Nothing to update.")
+			return nil
+		}
+
+		updated, err := a.UpdateItemByID(id, p)
+		if err != nil {
+			return err
+		}
+		if err := a.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Updated %s\n", updated)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	editCmd.Flags().String("title", "", "Item title")
+	viper.BindPFlag("edit.title", editCmd.Flags().Lookup("title"))
+
+	editCmd.Flags().String("namespace", "", "Namespace")
+	viper.BindPFlag("edit.namespace", editCmd.Flags().Lookup("namespace"))
+
+	editCmd.Flags().String("username", "", "Username")
+	viper.BindPFlag("edit.username", editCmd.Flags().Lookup("username"))
+
+	editCmd.Flags().String("password", "", "Password (not recommended, use the interactive editor)")
+	viper.BindPFlag("edit.password", editCmd.Flags().Lookup("password"))
+
+	editCmd.Flags().String("site", "", "Site host name. eg. gmail.com, github.com")
+	viper.BindPFlag("edit.site", editCmd.Flags().Lookup("site"))
+
+	editCmd.Flags().String("url", "", "Site login url")
+	viper.BindPFlag("edit.url", editCmd.Flags().Lookup("url"))
+
+	editCmd.Flags().String("host", "", "SSH host name. eg. example.com")
+	viper.BindPFlag("edit.host", editCmd.Flags().Lookup("host"))
+
+	editCmd.Flags().Uint16("port", 22, "SSH port")
+	viper.BindPFlag("edit.port", editCmd.Flags().Lookup("port"))
+
+	editCmd.Flags().String("key-file", "", "Replace the private key of ssh items with this file, empty to remove it")
+	viper.BindPFlag("edit.key-file", editCmd.Flags().Lookup("key-file"))
+
+	editCmd.Flags().String("totp", "", "Set the TOTP, otpauth:// URI or base32 secret, empty to remove it")
+	viper.BindPFlag("edit.totp", editCmd.Flags().Lookup("totp"))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// privateTempDir creates a directory only the user can access, in memory
+// backed /dev/shm if possible, so the secrets don't hit the disk.
+func privateTempDir() (string, error) {
+	base := ""
+	if runtime.GOOS == "linux" {
+		if fi, err := os.Stat("/dev/shm"); err == nil && fi.IsDir() {
+			base = "/dev/shm"
+		}
+	}
+	dir, err := os.MkdirTemp(base, "mypass-")
+	if err != nil && base != "" {
+		dir, err = os.MkdirTemp("", "mypass-")
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// shred overwrites the file with zeros before removing it.
+func shred(file string) error {
+	f, err := os.OpenFile(file, os.O_WRONLY, 0)
+	if err == nil {
+		if fi, err := f.Stat(); err == nil {
+			f.Write(make([]byte, fi.Size()))
+			f.Sync()
+		}
+		f.Close()
+	}
+	return os.Remove(file)
+}
+
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if v := strings.Fields(os.Getenv(env)); len(v) > 0 {
+			return v
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editSecret opens $VISUAL or $EDITOR on a temporary file with content and
+// returns the saved content. The file is in a private directory and it's
+// overwritten before it's removed.
+func editSecret(content []byte) ([]byte, error) {
+	if !stdinIsTerminal() {
+		return nil, errors.New("editor requires a terminal")
+	}
+	dir, err := privateTempDir()
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "note.txt")
+	defer shred(file)
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		return nil, err
+	}
+
+	editor := editorCommand()
+	c := exec.Command(editor[0], append(editor[1:], file)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+	return os.ReadFile(file)
+}
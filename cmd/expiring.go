@@ -0,0 +1,140 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type expiringEntry struct {
+	ID        int             `json:"id"`
+	Namespace string          `json:"namespace"`
+	Title     string          `json:"title"`
+	Type      models.ItemType `json:"type"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Expired   bool            `json:"expired"`
+	// Whole days until the expiry, negative once expired
+	DaysLeft int `json:"days_left"`
+}
+
+// expiringItems returns the items which expire before now+within, the
+// ones already expired included, ordered by their expiry.
+func expiringItems(items []*models.Item, now time.Time, within time.Duration) []expiringEntry {
+	entries := []expiringEntry{}
+	for _, i := range items {
+		t, ok := i.ExpiresAt()
+		if !ok || t.After(now.Add(within)) {
+			continue
+		}
+		left := t.Sub(now)
+		entries = append(entries, expiringEntry{
+			ID:        i.ID,
+			Namespace: i.Namespace,
+			Title:     i.Title,
+			Type:      i.GetType(),
+			ExpiresAt: t,
+			Expired:   left <= 0,
+			DaysLeft:  int(math.Ceil(left.Hours() / 24)),
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ExpiresAt.Before(entries[j].ExpiresAt)
+	})
+	return entries
+}
+
+// expiringCmd represents the expiring command
+var expiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "List items which expire soon",
+	Long: `List API keys, cards and other items which expire within the given
+duration, or have already expired.
+
+Secret fields are never decrypted, so it can run unattended, eg. from cron
+with the JSON output.`,
+	Example: `  mypass expiring --within 30d
+  mypass expiring --within 7d -o json`,
+	Args:              cobra.NoArgs,
+	SilenceUsage:      true,
+	PersistentPreRunE: skipDecryption,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		within, err := parseDuration(viper.GetString("expiring.within"))
+		if err != nil {
+			return err
+		}
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		items, err := a.ListAllItems()
+		if err != nil {
+			return err
+		}
+		entries := expiringItems(models.ActiveItems(items), time.Now(), within)
+
+		switch format := viper.GetString("expiring.output"); format {
+		case "table":
+			if len(entries) == 0 {
+				fmt.Println("Nothing expires in that time.")
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAMESPACE\tTITLE\tTYPE\tEXPIRES\tDAYS LEFT")
+			for _, e := range entries {
+				left := fmt.Sprint(e.DaysLeft)
+				if e.Expired {
+					left = "expired"
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+					e.ID, e.Namespace, e.Title, e.Type, e.ExpiresAt.Local().Format("2006-01-02"), left)
+			}
+			return w.Flush()
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		default:
+			return fmt.Errorf("unknown output format %q, use one of table, json", format)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(expiringCmd)
+
+	expiringCmd.Flags().String("within", "30d", "Report items expiring within this duration, eg. 30d, 12h")
+	viper.BindPFlag("expiring.within", expiringCmd.Flags().Lookup("within"))
+
+	expiringCmd.Flags().StringP("output", "o", "table", "Output format: table, json")
+	viper.BindPFlag("expiring.output", expiringCmd.Flags().Lookup("output"))
+}
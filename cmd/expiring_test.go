@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/riadafridishibly/mypass/models"
+)
+
+func TestExpiringWithoutPassword(t *testing.T) {
+	expires := time.Now().Add(24 * time.Hour)
+	v := newTestVault(t, &models.Item{
+		Title:     "ci",
+		Namespace: "default",
+		Type:      models.ItemAPIKey,
+		APIKey:    &models.APIKeyItem{Service: "github", Secret: "token", ExpiresAt: &expires},
+	})
+	// Neither the master password nor the private keys are available
+	out, err := v.run(t, "", "expiring", "--within", "7d", "-o", "json")
+	if err != nil {
+		t.Fatal("Failed to run expiring:", err)
+	}
+
+	var entries []expiringEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatal("Failed to decode the output:", err)
+	}
+	if len(entries) != 1 || entries[0].Title != "ci" || entries[0].Expired {
+		t.Fatal("Unexpected entries:", entries)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/manifoldco/promptui"
+	"github.com/riadafridishibly/mypass/generate"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+func generateOptionsFromConfig() generate.Options {
+	o := generate.DefaultOptions
+	o.Length = viper.GetInt("generate.size")
+	o.Lower = !viper.GetBool("generate.no-lower")
+	o.Upper = !viper.GetBool("generate.no-upper")
+	o.Digits = !viper.GetBool("generate.no-number")
+	o.Symbols = !viper.GetBool("generate.no-special")
+	o.ExcludeAmbiguous = viper.GetBool("generate.no-ambiguous")
+	o.MinLower = viper.GetInt("generate.min-lower")
+	o.MinUpper = viper.GetInt("generate.min-upper")
+	o.MinDigits = viper.GetInt("generate.min-number")
+	o.MinSymbols = viper.GetInt("generate.min-special")
+	return o
+}
+
+func passphraseOptionsFromConfig() generate.PassphraseOptions {
+	return generate.PassphraseOptions{
+		Words:      viper.GetInt("generate.words"),
+		Separator:  viper.GetString("generate.separator"),
+		Capitalize: viper.GetBool("generate.capitalize"),
+		Digit:      viper.GetBool("generate.digit"),
+	}
+}
+
+// generateFromConfig generates a password or a passphrase with the
+// options from the flags and returns it with its entropy in bits.
+func generateFromConfig() (string, float64, error) {
+	if viper.GetBool("generate.passphrase") {
+		o := passphraseOptionsFromConfig()
+		pass, err := generate.Passphrase(o)
+		if err != nil {
+			return "", 0, err
+		}
+		bits, err := generate.PassphraseEntropy(o)
+		return pass, bits, err
+	}
+	o := generateOptionsFromConfig()
+	pass, err := generate.Password(o)
+	if err != nil {
+		return "", 0, err
+	}
+	bits, err := generate.Entropy(o)
+	return pass, bits, err
+}
+
+// readOrGeneratePassword offers to generate a password, and reads one
+// from the terminal if the offer is declined.
+func readOrGeneratePassword() (string, error) {
+	err := confirm("Password is empty, generate one")
+	if err == nil {
+		o := generate.DefaultOptions
+		pass, err := generate.Password(o)
+		if err != nil {
+			return "", err
+		}
+		bits, _ := generate.Entropy(o)
+		fmt.Printf("Generated a %d character password (~%.0f bits of entropy)\n", o.Length, bits)
+		return pass, nil
+	}
+	if !errors.Is(err, promptui.ErrAbort) {
+		return "", err
+	}
+	// try read password from stdin
+	fmt.Print("Enter password:")
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		return "", err
+	}
+	fmt.Println()
+	return string(data), nil
+}
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a random password or passphrase",
+	Long: `Generate a random password or passphrase.
+
+Passphrases (--passphrase) are made of words from EFF's large wordlist.`,
+	Aliases:      []string{"gen"},
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipBackend: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pass, bits, err := generateFromConfig()
+		if err != nil {
+			return err
+		}
+		if viper.GetBool("generate.copy") {
+			if err := copyToClipboard([]byte(pass)); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Password copied to clipboard (~%.0f bits of entropy).\n", bits)
+			return nil
+		}
+		fmt.Println(pass)
+		fmt.Fprintf(os.Stderr, "Entropy: ~%.0f bits\n", bits)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+
+	generateCmd.Flags().Int("size", generate.DefaultOptions.Length, "Password length")
+	viper.BindPFlag("generate.size", generateCmd.Flags().Lookup("size"))
+
+	generateCmd.Flags().Bool("no-lower", false, "Don't use lower case letters")
+	viper.BindPFlag("generate.no-lower", generateCmd.Flags().Lookup("no-lower"))
+
+	generateCmd.Flags().Bool("no-upper", false, "Don't use upper case letters")
+	viper.BindPFlag("generate.no-upper", generateCmd.Flags().Lookup("no-upper"))
+
+	generateCmd.Flags().Bool("no-number", false, "Don't use digits")
+	viper.BindPFlag("generate.no-number", generateCmd.Flags().Lookup("no-number"))
+
+	generateCmd.Flags().Bool("no-special", false, "Don't use special characters")
+	viper.BindPFlag("generate.no-special", generateCmd.Flags().Lookup("no-special"))
+
+	generateCmd.Flags().Bool("no-ambiguous", false, "Don't use characters that are easy to confuse, eg. l, 1, O, 0")
+	viper.BindPFlag("generate.no-ambiguous", generateCmd.Flags().Lookup("no-ambiguous"))
+
+	generateCmd.Flags().Int("min-lower", generate.DefaultOptions.MinLower, "Minimum number of lower case letters")
+	viper.BindPFlag("generate.min-lower", generateCmd.Flags().Lookup("min-lower"))
+
+	generateCmd.Flags().Int("min-upper", generate.DefaultOptions.MinUpper, "Minimum number of upper case letters")
+	viper.BindPFlag("generate.min-upper", generateCmd.Flags().Lookup("min-upper"))
+
+	generateCmd.Flags().Int("min-number", generate.DefaultOptions.MinDigits, "Minimum number of digits")
+	viper.BindPFlag("generate.min-number", generateCmd.Flags().Lookup("min-number"))
+
+	generateCmd.Flags().Int("min-special", generate.DefaultOptions.MinSymbols, "Minimum number of special characters")
+	viper.BindPFlag("generate.min-special", generateCmd.Flags().Lookup("min-special"))
+
+	generateCmd.Flags().Bool("passphrase", false, "Generate a passphrase of random words")
+	viper.BindPFlag("generate.passphrase", generateCmd.Flags().Lookup("passphrase"))
+
+	generateCmd.Flags().Int("words", generate.DefaultPassphraseOptions.Words, "Number of words in the passphrase")
+	viper.BindPFlag("generate.words", generateCmd.Flags().Lookup("words"))
+
+	generateCmd.Flags().String("separator", generate.DefaultPassphraseOptions.Separator, "Separator between the words of the passphrase")
+	viper.BindPFlag("generate.separator", generateCmd.Flags().Lookup("separator"))
+
+	generateCmd.Flags().Bool("capitalize", false, "Capitalize the words of the passphrase")
+	viper.BindPFlag("generate.capitalize", generateCmd.Flags().Lookup("capitalize"))
+
+	generateCmd.Flags().Bool("digit", false, "Append a random digit to a random word of the passphrase")
+	viper.BindPFlag("generate.digit", generateCmd.Flags().Lookup("digit"))
+
+	generateCmd.Flags().BoolP("copy", "c", false, "Copy to clipboard instead of printing")
+	viper.BindPFlag("generate.copy", generateCmd.Flags().Lookup("copy"))
+}
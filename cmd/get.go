@@ -0,0 +1,256 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+)
+
+// activeItemByID looks up an item by id, items in the trash are not
+// found.
+func activeItemByID(a backend.Backend, id int) (*models.Item, error) {
+	i, err := a.GetItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if i.IsTrashed() {
+		return nil, fmt.Errorf("%w: item %d is in the trash", models.ErrItemNotFound, id)
+	}
+	return i, nil
+}
+
+// findItem looks up an item by id, title or namespace/title. Items in
+// the trash are not found.
+func findItem(a backend.Backend, query string) (*models.Item, error) {
+	if id, err := strconv.Atoi(query); err == nil {
+		return activeItemByID(a, id)
+	}
+	all, err := a.ListAllItems()
+	if err != nil {
+		return nil, err
+	}
+	items := models.ActiveItems(all)
+	match := func(i *models.Item, eq func(a, b string) bool) bool {
+		if eq(i.Title, query) {
+			return true
+		}
+		ns, title, ok := strings.Cut(query, "/")
+		return ok && eq(i.Namespace, ns) && eq(i.Title, title)
+	}
+	exact := func(a, b string) bool { return a == b }
+	// Prefer exact matches, fall back to case insensitive ones
+	for _, eq := range []func(a, b string) bool{exact, strings.EqualFold} {
+		var found []*models.Item
+		for _, i := range items {
+			if match(i, eq) {
+				found = append(found, i)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], nil
+		}
+		if len(found) > 1 {
+			var candidates []string
+			for _, i := range found {
+				candidates = append(candidates, fmt.Sprintf("%d (%s/%s)", i.ID, i.Namespace, i.Title))
+			}
+			return nil, fmt.Errorf("%w: %q matches %s, use the id or namespace/title",
+				models.ErrAmbiguousItem, query, strings.Join(candidates, ", "))
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", models.ErrItemNotFound, query)
+}
+
+// itemField returns a single field of the item as plain text.
+func itemField(i *models.Item, field string) (string, error) {
+	switch field {
+	case "title":
+		return i.Title, nil
+	case "namespace":
+		return i.Namespace, nil
+	}
+	switch {
+	case i.Password != nil:
+		switch field {
+		case "password":
+			return string(i.Password.Password), nil
+		case "username":
+			return i.Password.Username, nil
+		case "site":
+			return i.Password.SiteName, nil
+		case "url":
+			return i.Password.URL, nil
+		}
+	case i.SSH != nil:
+		switch field {
+		case "password":
+			return string(i.SSH.Password), nil
+		case "username":
+			return i.SSH.Username, nil
+		case "host":
+			return i.SSH.Host, nil
+		case "port":
+			return strconv.FormatUint(uint64(i.SSH.Port), 10), nil
+		case "private-key":
+			return string(i.SSH.PrivateKey), nil
+		case "passphrase":
+			return string(i.SSH.Passphrase), nil
+		case "public-key":
+			pub, err := i.SSH.PublicKey()
+			if err != nil {
+				return "", err
+			}
+			return string(ssh.MarshalAuthorizedKey(pub)), nil
+		}
+	}
+	if i.Note != nil && field == "body" {
+		return string(i.Note.Body), nil
+	}
+	if k := i.APIKey; k != nil {
+		switch field {
+		case "service":
+			return k.Service, nil
+		case "key-id":
+			return k.KeyID, nil
+		case "secret":
+			return string(k.Secret), nil
+		case "scopes":
+			return strings.Join(k.Scopes, ","), nil
+		case "expires":
+			return formatExpiry(k.ExpiresAt), nil
+		}
+	}
+	if c := i.Card; c != nil {
+		switch field {
+		case "cardholder":
+			return c.Cardholder, nil
+		case "brand":
+			return c.Brand, nil
+		case "number":
+			return string(c.Number), nil
+		case "expiry":
+			return c.Expiry, nil
+		case "cvv":
+			return string(c.CVV), nil
+		case "pin":
+			return string(c.PIN), nil
+		}
+	}
+	if i.TOTP != nil {
+		switch field {
+		case "code":
+			code, _, err := totpCode(i)
+			return code, err
+		case "secret":
+			return string(i.TOTP.Secret), nil
+		case "issuer":
+			return i.TOTP.Issuer, nil
+		case "account":
+			return i.TOTP.Account, nil
+		}
+	}
+	return "", fmt.Errorf("item %d has no field %q", i.ID, field)
+}
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get <id|title|namespace/title>",
+	Short: "Print a field or the whole item",
+	Long: `Print a field or the whole item.
+
+The item is looked up by id, title or namespace/title. By default the password,
+the current code of TOTP items, the body of notes, the card number, the
+secret of API keys or the public key of ssh items without a password is
+printed, so it can be used in scripts like
+
+  export TOKEN="$(mypass get github.com)"`,
+	Aliases:      []string{"show"},
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := findItem(a, args[0])
+		if err != nil {
+			return err
+		}
+		if viper.GetBool("get.json") {
+			data, err := i.MarshalPlainJSON()
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		field := viper.GetString("get.field")
+		if !cmd.Flags().Changed("field") {
+			switch i.GetType() {
+			case models.ItemTOTP:
+				field = "code"
+			case models.ItemNote:
+				field = "body"
+			case models.ItemCard:
+				field = "number"
+			case models.ItemAPIKey:
+				field = "secret"
+			case models.ItemSSH:
+				if i.SSH.Password == "" && i.SSH.HasKey() {
+					field = "public-key"
+				}
+			}
+		}
+		v, err := itemField(i, field)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(v, "\n") {
+			// Notes usually end with a newline
+			fmt.Print(v)
+			return nil
+		}
+		fmt.Println(v)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().StringP("field", "f", "password", "Field to print: password, username, title, namespace, site, url, host, port, private-key, passphrase, public-key, code, secret, issuer, account, body, cardholder, brand, number, expiry, cvv, pin, service, key-id, scopes, expires")
+	viper.BindPFlag("get.field", getCmd.Flags().Lookup("field"))
+
+	getCmd.Flags().Bool("json", false, "Print the whole decrypted item as JSON")
+	viper.BindPFlag("get.json", getCmd.Flags().Lookup("json"))
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show the previous passwords of an item",
+	Long: `Show the previous passwords of an item, newest first.
+
+Whenever the password of an item changes the old one is kept, up to
+password_history entries per item (default 5). The passwords are masked
+unless --reveal is given.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseItemID(args[0])
+		if err != nil {
+			return err
+		}
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := activeItemByID(a, id)
+		if err != nil {
+			return err
+		}
+		if len(i.History) == 0 {
+			fmt.Printf("Item %d has no password history.\n", id)
+			return nil
+		}
+		reveal := viper.GetBool("history.reveal")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "N\tREPLACED\tPASSWORD")
+		for n, e := range i.History {
+			password := "********"
+			if reveal {
+				password = string(e.Password)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", n+1, e.ReplacedAt.Local().Format("2006-01-02 15:04:05"), password)
+		}
+		return w.Flush()
+	},
+}
+
+// historyRestoreCmd represents the history restore command
+var historyRestoreCmd = &cobra.Command{
+	Use:   "restore <id> <n>",
+	Short: "Make a previous password the current one",
+	Long: `Make a previous password the current one.
+
+n is the number shown by "mypass history <id>". The replaced password is
+kept in the history, so a restore can be undone the same way.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseItemID(args[0])
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid history entry %q", args[1])
+		}
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := activeItemByID(a, id)
+		if err != nil {
+			return err
+		}
+		if n < 1 || n > len(i.History) {
+			return fmt.Errorf("item %d has %d history entries, got %d", id, len(i.History), n)
+		}
+		if viper.GetInt(vkeys.PasswordHistory) < 1 {
+			// The current password would be lost
+			return fmt.Errorf("%s is disabled, refusing to replace the current password", vkeys.PasswordHistory)
+		}
+		e := i.History[n-1]
+		p := &models.ItemPatch{}
+		switch {
+		case i.Password != nil:
+			p.Password = &models.PasswordItemPatch{Password: &e.Password}
+		case i.SSH != nil:
+			p.SSH = &models.SSHItemPatch{Password: &e.Password}
+		case i.APIKey != nil:
+			p.APIKey = &models.APIKeyItemPatch{Secret: &e.Password}
+		default:
+			return fmt.Errorf("item %d has no password", id)
+		}
+		updated, err := a.UpdateItemByID(id, p)
+		if err != nil {
+			return err
+		}
+		if err := a.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Restored password from %s for %s\n", e.ReplacedAt.Local().Format(time.RFC3339), updated)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyRestoreCmd)
+
+	historyCmd.Flags().Bool("reveal", false, "Show the passwords in plain text")
+	viper.BindPFlag("history.reveal", historyCmd.Flags().Lookup("reveal"))
+}
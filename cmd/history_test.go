@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+func TestTrashedItemNotFound(t *testing.T) {
+	deleted := time.Now()
+	v := newTestVault(t, &models.Item{
+		Title:     "old",
+		Namespace: "default",
+		Type:      models.ItemPassword,
+		Meta:      models.Meta{DeletedAt: &deleted},
+		Password:  &models.PasswordItem{Username: "me", Password: "s3cret"},
+		History:   models.PasswordHistory{{Password: "older", ReplacedAt: deleted}},
+	})
+	viper.Set(vkeys.Password, "test")
+	id := "1"
+	for _, args := range [][]string{
+		{"history", id},
+		{"history", "restore", id, "1"},
+		{"edit", id, "--title", "new"},
+	} {
+		if _, err := v.run(t, "", args...); !errors.Is(err, models.ErrItemNotFound) {
+			t.Fatalf("Expected %v from %v, found: %v", models.ErrItemNotFound, args, err)
+		}
+	}
+	items := v.items(t)
+	if len(items) != 1 || items[0].Title != "old" || items[0].Password.Password != "s3cret" {
+		t.Fatal("Trashed item is changed:", items[0])
+	}
+}
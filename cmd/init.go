@@ -23,7 +23,6 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -33,6 +32,7 @@ import (
 	"filippo.io/age"
 	"github.com/riadafridishibly/mypass/backend"
 	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/generate"
 	"github.com/riadafridishibly/mypass/models"
 	"github.com/riadafridishibly/mypass/vkeys"
 	"github.com/spf13/cobra"
@@ -40,31 +40,62 @@ import (
 	"golang.org/x/term"
 )
 
-func initPrivateKeys(file string) (pubKeys []string, err error) {
-	// If err == nil; file exists
-	if _, err := os.Stat(file); err == nil {
-		return nil, errors.New("private key file exists")
-	}
-
-	fmt.Print("Enter your master password: ")
+// readNewPassword reads a password twice from the terminal and
+// returns it if both match.
+func readNewPassword(label string) ([]byte, error) {
+	fmt.Printf("Enter your %s: ", label)
 	password, err := term.ReadPassword(int(os.Stdin.Fd()))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read master password: %w", err)
-		// jww.FATAL.Fatal("Failed to read password")
+		return nil, fmt.Errorf("failed to read %s: %w", label, err)
 	}
 	fmt.Println()
-	fmt.Print("Enter your master password (again): ")
+	fmt.Printf("Enter your %s (again): ", label)
 	password2, err := term.ReadPassword(int(os.Stdin.Fd()))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read master password: %w", err)
-		// jww.FATAL.Fatal("Failed to read password")
+		return nil, fmt.Errorf("failed to read %s: %w", label, err)
 	}
 	fmt.Println()
 
 	if !bytes.Equal(password, password2) {
-		// jww.ERROR.Fatal("Password didn't match")
 		return nil, fmt.Errorf("password didn't match")
 	}
+	return password, nil
+}
+
+// suggestPassphrase prints a random passphrase that can be used
+// as the master password.
+func suggestPassphrase() error {
+	o := generate.DefaultPassphraseOptions
+	o.Capitalize = true
+	o.Digit = true
+	pass, err := generate.Passphrase(o)
+	if err != nil {
+		return err
+	}
+	bits, err := generate.PassphraseEntropy(o)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Suggested master password (~%.0f bits of entropy): %s\n", bits, pass)
+	return nil
+}
+
+func initPrivateKeys(file string) (pubKeys []string, err error) {
+	// If err == nil; file exists
+	if _, err := os.Stat(file); err == nil {
+		return nil, errors.New("private key file exists")
+	}
+
+	if viper.GetBool("init.suggest") {
+		if err := suggestPassphrase(); err != nil {
+			return nil, err
+		}
+	}
+
+	password, err := readNewPassword("master password")
+	if err != nil {
+		return nil, err
+	}
 
 	viper.Set(vkeys.Password, string(password))
 
@@ -81,18 +112,11 @@ func initPrivateKeys(file string) (pubKeys []string, err error) {
 			models.SymSecretStr(identity.String()),
 		},
 	}
-	// OpenFile(name, O_RDWR|O_CREATE|O_TRUNC, 0666)
-	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	err = json.NewEncoder(f).Encode(privKeys)
+	err = config.WritePrivateKeys(file, &privKeys)
 	if err != nil {
 		return nil, err
 	}
-	return []string{identity.Recipient().String()}, err
+	return []string{identity.Recipient().String()}, nil
 }
 
 var ErrConfigExits = errors.New("config already exits")
@@ -134,7 +158,11 @@ func initPrivateKeysAndDb() error {
 		return err
 	}
 
-	return b.AddPublicKeys(pubKeys...)
+	err = b.AddPublicKeys(pubKeys...)
+	if err != nil {
+		return err
+	}
+	return b.Flush()
 }
 
 // initCmd represents the init command
@@ -163,4 +191,7 @@ In case of error it'll report them in stdout.`,
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().Bool("suggest", false, "Suggest a random passphrase for the master password")
+	viper.BindPFlag("init.suggest", initCmd.Flags().Lookup("suggest"))
 }
@@ -0,0 +1,86 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage your private keys",
+}
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Generate a new private key and encrypt all items for it",
+	Long: `Generate a new private key and encrypt all items for it.
+
+The new key is appended to the private key file first, then every item is
+encrypted again for the new set of public keys. With --retire the older
+private keys and their public keys are removed afterwards.
+
+If a rotation is interrupted, running the command again resumes it, with
+the same --retire as the interrupted run.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	// The database is opened after the new key is stored
+	Annotations: map[string]string{skipBackend: "true"},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath := viper.GetString(vkeys.DatabasePath)
+		file := viper.GetString(vkeys.PrivateKeysPath)
+		rotation, resumed, err := backend.StartKeyRotation(dbPath, file, viper.GetBool("keys.rotate.retire"))
+		if err != nil {
+			return err
+		}
+		if resumed {
+			fmt.Printf("Resuming key rotation started at %s\n", rotation.StartedAt.Format(time.RFC3339))
+		}
+		newPub, retired, err := backend.FinishKeyRotation(backend.Type(), dbPath, file)
+		if err != nil {
+			return err
+		}
+		fmt.Println("New public key:", newPub)
+		if rotation.Retire {
+			fmt.Printf("Retired %d old key(s).\n", len(retired))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysRotateCmd)
+
+	keysRotateCmd.Flags().Bool("retire", false, "Remove the old private keys after rotation")
+	viper.BindPFlag("keys.rotate.retire", keysRotateCmd.Flags().Lookup("retire"))
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type listEntry struct {
+	ID        int             `json:"id"`
+	Namespace string          `json:"namespace"`
+	Title     string          `json:"title"`
+	Type      models.ItemType `json:"type"`
+	Summary   string          `json:"summary,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func newListEntry(i *models.Item) listEntry {
+	e := listEntry{
+		ID:        i.ID,
+		Namespace: i.Namespace,
+		Title:     i.Title,
+		Type:      i.GetType(),
+		CreatedAt: i.Meta.CreatedAt,
+		UpdatedAt: i.Meta.UpdatedAt,
+	}
+	if e.Type != "" {
+		e.Summary = i.InnerItemString()
+	}
+	return e
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func filterItems(items []*models.Item) ([]*models.Item, error) {
+	ns := viper.GetString("list.namespace")
+	typ := models.ItemType(viper.GetString("list.type"))
+	title := strings.ToLower(viper.GetString("list.title"))
+	var out []*models.Item
+	for _, i := range items {
+		if ns != "" && i.Namespace != ns {
+			continue
+		}
+		if typ != "" && i.GetType() != typ {
+			continue
+		}
+		if title != "" && !strings.Contains(strings.ToLower(i.Title), title) {
+			continue
+		}
+		out = append(out, i)
+	}
+	var less func(a, b *models.Item) bool
+	switch sortBy := viper.GetString("list.sort"); sortBy {
+	case "id":
+		less = func(a, b *models.Item) bool { return a.ID < b.ID }
+	case "title":
+		less = func(a, b *models.Item) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case "created":
+		less = func(a, b *models.Item) bool { return a.Meta.CreatedAt.Before(b.Meta.CreatedAt) }
+	case "updated":
+		less = func(a, b *models.Item) bool { return a.Meta.UpdatedAt.Before(b.Meta.UpdatedAt) }
+	default:
+		return nil, fmt.Errorf("unknown sort key %q, use one of id, title, created, updated", sortBy)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+	if viper.GetBool("list.reverse") {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out, nil
+}
+
+func printItems(items []*models.Item, format string) error {
+	entries := make([]listEntry, 0, len(items))
+	for _, i := range items {
+		entries = append(entries, newListEntry(i))
+	}
+	switch format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAMESPACE\tTITLE\tTYPE\tSUMMARY\tUPDATED")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				e.ID, e.Namespace, e.Title, e.Type, e.Summary, formatTime(e.UpdatedAt))
+		}
+		return w.Flush()
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"id", "namespace", "title", "type", "summary", "created_at", "updated_at"})
+		for _, e := range entries {
+			w.Write([]string{
+				strconv.Itoa(e.ID), e.Namespace, e.Title, string(e.Type), e.Summary,
+				e.CreatedAt.Format(time.RFC3339), e.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	return fmt.Errorf("unknown output format %q, use one of table, json, csv", format)
+}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List items without their secrets",
+	Long: `List items without their secrets.
+
+Secret fields are never decrypted, use get or select to read them.`,
+	Aliases:      []string{"ls"},
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	// Also run for namespaces
+	PersistentPreRunE: skipDecryption,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		all, err := a.ListAllItems()
+		if err != nil {
+			return err
+		}
+		items, err := filterItems(models.ActiveItems(all))
+		if err != nil {
+			return err
+		}
+		return printItems(items, viper.GetString("list.output"))
+	},
+}
+
+var listNamespacesCmd = &cobra.Command{
+	Use:          "namespaces",
+	Short:        "List namespaces with their number of items",
+	Aliases:      []string{"ns"},
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		items, err := a.ListAllItems()
+		if err != nil {
+			return err
+		}
+		counts := models.NamespaceCounts(models.ActiveItems(items))
+		names := make([]string, 0, len(counts))
+		for ns := range counts {
+			names = append(names, ns)
+		}
+		sort.Strings(names)
+
+		switch format := viper.GetString("list.output"); format {
+		case "table":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAMESPACE\tITEMS")
+			for _, ns := range names {
+				fmt.Fprintf(w, "%s\t%d\n", ns, counts[ns])
+			}
+			return w.Flush()
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(counts)
+		case "csv":
+			w := csv.NewWriter(os.Stdout)
+			w.Write([]string{"namespace", "items"})
+			for _, ns := range names {
+				w.Write([]string{ns, strconv.Itoa(counts[ns])})
+			}
+			w.Flush()
+			return w.Error()
+		default:
+			return fmt.Errorf("unknown output format %q, use one of table, json, csv", format)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.AddCommand(listNamespacesCmd)
+
+	listCmd.PersistentFlags().StringP("output", "o", "table", "Output format: table, json, csv")
+	viper.BindPFlag("list.output", listCmd.PersistentFlags().Lookup("output"))
+
+	listCmd.Flags().StringP("namespace", "n", "", "Only items in this namespace")
+	viper.BindPFlag("list.namespace", listCmd.Flags().Lookup("namespace"))
+
+	listCmd.Flags().StringP("type", "t", "", "Only items of this type: password, ssh, totp, note, card, apikey")
+	viper.BindPFlag("list.type", listCmd.Flags().Lookup("type"))
+
+	listCmd.Flags().String("title", "", "Only items with title containing this text")
+	viper.BindPFlag("list.title", listCmd.Flags().Lookup("title"))
+
+	listCmd.Flags().StringP("sort", "s", "title", "Sort by: id, title, created, updated")
+	viper.BindPFlag("list.sort", listCmd.Flags().Lookup("sort"))
+
+	listCmd.Flags().BoolP("reverse", "r", false, "Reverse the order")
+	viper.BindPFlag("list.reverse", listCmd.Flags().Lookup("reverse"))
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/spf13/viper"
+)
+
+// copyVault copies the public keys and every item from src to dst
+// and returns the number of items copied.
+func copyVault(src, dst backend.Backend) (int, error) {
+	keys, err := src.PublicKeys()
+	if err != nil {
+		return 0, err
+	}
+	if err := dst.AddPublicKeys(keys...); err != nil {
+		return 0, err
+	}
+	items, err := src.ListAllItems()
+	if err != nil {
+		return 0, err
+	}
+	for _, i := range items {
+		// Copy, so the source is not changed by the target backend
+		v := *i
+		if _, err := dst.CreateItem(&v); err != nil {
+			return 0, fmt.Errorf("failed to copy item %d: %w", i.ID, err)
+		}
+	}
+	return len(items), dst.Flush()
+}
+
+func truncateMeta(m models.Meta) models.Meta {
+	v := models.Meta{
+		CreatedAt: m.CreatedAt.Truncate(time.Second).UTC(),
+		UpdatedAt: m.UpdatedAt.Truncate(time.Second).UTC(),
+	}
+	if m.DeletedAt != nil {
+		t := m.DeletedAt.Truncate(time.Second).UTC()
+		v.DeletedAt = &t
+	}
+	return v
+}
+
+// verifyVault checks that dst holds the same public keys and items as src,
+// compared with all the secrets decrypted.
+func verifyVault(src, dst backend.Backend) error {
+	srcKeys, err := src.PublicKeys()
+	if err != nil {
+		return err
+	}
+	dstKeys, err := dst.PublicKeys()
+	if err != nil {
+		return err
+	}
+	if fmt.Sprint(srcKeys) != fmt.Sprint(dstKeys) {
+		return errors.New("public keys don't match")
+	}
+	srcItems, err := src.ListAllItems()
+	if err != nil {
+		return err
+	}
+	dstItems, err := dst.ListAllItems()
+	if err != nil {
+		return err
+	}
+	if len(srcItems) != len(dstItems) {
+		return fmt.Errorf("item count doesn't match, source has %d, target has %d", len(srcItems), len(dstItems))
+	}
+	byID := make(map[int]*models.Item, len(dstItems))
+	for _, i := range dstItems {
+		byID[i.ID] = i
+	}
+	for _, want := range srcItems {
+		got, ok := byID[want.ID]
+		if !ok {
+			return fmt.Errorf("item %d is missing in target", want.ID)
+		}
+		// The sqlite backend stores timestamps with second precision
+		w, g := *want, *got
+		w.Meta, g.Meta = truncateMeta(want.Meta), truncateMeta(got.Meta)
+		a, err := w.MarshalPlainJSON()
+		if err != nil {
+			return err
+		}
+		b, err := g.MarshalPlainJSON()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(a, b) {
+			return fmt.Errorf("item %d doesn't match", want.ID)
+		}
+	}
+	return nil
+}
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate --to json|sqlite3 --dest <path>",
+	Short: "Copy the vault to a new database of another backend",
+	Long: `Copy the vault to a new database of another backend.
+
+The public keys and all items, with their ids, namespaces and timestamps, are
+copied into a new database at --dest, which must not exist. The copy is read
+back and compared with the source. With --update-config the config file is
+changed to use the new database.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to := viper.GetString("migrate.to")
+		dest := viper.GetString("migrate.dest")
+		if strings.HasPrefix(dest, "~/") {
+			dest = config.ExpandWithHome(dest)
+		}
+		dest, err := filepath.Abs(dest)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(dest); err == nil {
+			return fmt.Errorf("%s already exists", dest)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+			return err
+		}
+
+		src, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		n, err := func() (int, error) {
+			dst, err := backend.Open(to, dest)
+			if err != nil {
+				return 0, err
+			}
+			n, err := copyVault(src, dst)
+			if err != nil {
+				dst.Close()
+				return 0, err
+			}
+			// Read back what is written
+			dst, err = backend.Open(to, dest)
+			if err != nil {
+				return 0, err
+			}
+			defer dst.Close()
+			return n, verifyVault(src, dst)
+		}()
+		if err != nil {
+			if rerr := backend.RemoveDatabase(dest); rerr != nil {
+				jww.ERROR.Println("failed to remove the new database:", rerr)
+			}
+			return fmt.Errorf("migration failed, %s is removed: %w", dest, err)
+		}
+		fmt.Printf("Copied %d items to %s (%s), verified.\n", n, dest, to)
+
+		if !viper.GetBool("migrate.update-config") {
+			return nil
+		}
+		file := viper.ConfigFileUsed()
+		if file == "" {
+			return errors.New("no config file is used, can't update it")
+		}
+		err = config.UpdateConfigFile(file, map[string]string{
+			"backend":          to,
+			vkeys.DatabasePath: dest,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated %s to use the new database.\n", file)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().String("to", "", "Target backend: json, sqlite3")
+	viper.BindPFlag("migrate.to", migrateCmd.Flags().Lookup("to"))
+	migrateCmd.MarkFlagRequired("to")
+
+	migrateCmd.Flags().String("dest", "", "Path of the new database")
+	viper.BindPFlag("migrate.dest", migrateCmd.Flags().Lookup("dest"))
+	migrateCmd.MarkFlagRequired("dest")
+
+	migrateCmd.Flags().Bool("update-config", false, "Change the config file to use the new database")
+	viper.BindPFlag("migrate.update-config", migrateCmd.Flags().Lookup("update-config"))
+}
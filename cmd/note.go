@@ -0,0 +1,116 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newDefaultNoteItem() *models.Item {
+	return &models.Item{
+		Title:     "",
+		Namespace: "default",
+		Type:      models.ItemNote,
+		Meta: models.Meta{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		Note: &models.NoteItem{},
+	}
+}
+
+// readNoteBody reads the body from stdin if it's not a terminal, the
+// editor is opened otherwise.
+func readNoteBody(cur string) (string, error) {
+	var body []byte
+	var err error
+	if stdinIsTerminal() {
+		body, err = editSecret([]byte(cur))
+	} else {
+		body, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(body)) == "" {
+		return "", errors.New("note is empty")
+	}
+	return string(body), nil
+}
+
+// noteCmd represents the add note command
+var noteCmd = &cobra.Command{
+	Use:   "note",
+	Short: "Add secure note",
+	Long: `Add secure note, for recovery codes, license keys and the like.
+
+The body is read from stdin, or when stdin is a terminal $VISUAL or $EDITOR
+is opened on a temporary file in a private directory. The file is overwritten
+and removed once the editor exits.`,
+	SilenceUsage: true,
+	Example: `  mypass add note --title "GitHub recovery codes" < codes.txt
+  mypass add note --title "Runbook"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		i := newDefaultNoteItem()
+		i.Namespace = viper.GetString("add.namespace")
+		i.Title = viper.GetString("add.title")
+		if i.Title == "" {
+			if !stdinIsTerminal() {
+				return errors.New("missing required flags: --title")
+			}
+			prompt := promptui.Prompt{
+				Label: "Title",
+				Validate: func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return errors.New("title can't be empty")
+					}
+					return nil
+				},
+			}
+			title, err := prompt.Run()
+			if err != nil {
+				return fmt.Errorf("prompt failed: %w", err)
+			}
+			i.Title = strings.TrimSpace(title)
+		}
+		body, err := readNoteBody("")
+		if err != nil {
+			return err
+		}
+		i.Note.Body = models.AsymSecretStr(body)
+		return createItem(i)
+	},
+}
+
+func init() {
+	addCmd.AddCommand(noteCmd)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// totpCode returns the current code of the item's TOTP.
+func totpCode(i *models.Item) (string, time.Duration, error) {
+	if i.TOTP == nil {
+		return "", 0, fmt.Errorf("item %d has no TOTP", i.ID)
+	}
+	return i.TOTP.Code(time.Now())
+}
+
+// otpCmd represents the otp command
+var otpCmd = &cobra.Command{
+	Use:   "otp <id|title|namespace/title>",
+	Short: "Print or copy the current TOTP code",
+	Long: `Print or copy the current TOTP code of a TOTP item or of a password item
+with an attached TOTP.
+
+The code is printed on stdout and the seconds it stays valid on stderr, so
+it can be used in scripts like
+
+  CODE="$(mypass otp github.com)"`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := findItem(a, args[0])
+		if err != nil {
+			return err
+		}
+		code, remaining, err := totpCode(i)
+		if err != nil {
+			return err
+		}
+		if viper.GetBool("otp.copy") {
+			if err := copyToClipboard([]byte(code)); err != nil {
+				return err
+			}
+			fmt.Printf("Code copied for %q to clipboard, valid for %ds.\n", i.Title, int(remaining.Seconds()))
+			return nil
+		}
+		fmt.Println(code)
+		fmt.Fprintf(os.Stderr, "valid for %ds\n", int(remaining.Seconds()))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(otpCmd)
+
+	otpCmd.Flags().BoolP("copy", "c", false, "Copy the code to the clipboard instead of printing it")
+	viper.BindPFlag("otp.copy", otpCmd.Flags().Lookup("copy"))
+}
@@ -23,15 +23,11 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
-	"os"
 	"time"
 
-	"github.com/riadafridishibly/mypass/backend"
 	"github.com/riadafridishibly/mypass/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/term"
 )
 
 func newDefaultPasswordItem() *models.Item {
@@ -111,48 +107,64 @@ const passDetailsTpl = `
 
 // passCmd represents the pass command
 var passCmd = &cobra.Command{
-	Use:   "pass",
-	Short: "Add password item",
+	Use:          "pass",
+	Short:        "Add password item",
+	SilenceUsage: true,
+	Example: `  mypass add pass --title GitHub --username me --site github.com
+  echo "$PASS" | mypass add pass --username me --site github.com --password-stdin`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		v, err := Prompt(
-			newPassFieldsWithConfig(newDefaultPasswordItem()),
-			passDetailsTpl,
-		)
+		i := newDefaultPasswordItem()
+		i.Namespace = viper.GetString("add.namespace")
+		i.Password.Username = viper.GetString("pass.username")
+		i.Password.SiteName = viper.GetString("pass.site")
+		i.Password.URL = viper.GetString("pass.url")
+		if title := viper.GetString("add.title"); title != "" {
+			i.Title = title
+		} else if i.Password.SiteName != "" {
+			i.Title = i.Password.SiteName
+		}
+		pass, err := passwordFromFlags("pass")
+		if err != nil {
+			return err
+		}
+		i.Password.Password = models.AsymSecretStr(pass)
+
+		var missing []string
+		if i.Password.Username == "" {
+			missing = append(missing, "--username")
+		}
+		if i.Password.SiteName == "" {
+			missing = append(missing, "--site")
+		}
+		prompt, err := needPrompt("pass", missing)
 		if err != nil {
 			return err
 		}
-		pass := v["Password"]
-		if pass == "" {
-			// try read password from stdin
-			fmt.Print("Enter password:")
-			data, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if prompt {
+			v, err := Prompt(newPassFieldsWithConfig(i), passDetailsTpl)
 			if err != nil {
 				return err
 			}
-			fmt.Println()
-			pass = string(data)
-		}
-
-		p := &models.PasswordItem{
-			Username: v["Username"],
-			SiteName: v["SiteName"],
-			URL:      v["URL"],
-			Password: models.AsymSecretStr(pass),
+			i.Title = v["Title"]
+			i.Namespace = v["Namespace"]
+			i.Password.Username = v["Username"]
+			i.Password.SiteName = v["SiteName"]
+			i.Password.URL = v["URL"]
+			pass = v["Password"]
 		}
 
-		a, err := backend.Get()
+		pass, err = ensurePassword(pass)
 		if err != nil {
 			return err
 		}
-		_, err = a.CreateItem(&models.Item{
-			Title:     v["Title"],
-			Namespace: v["Namespace"],
-			Password:  p,
-		})
-		if err != nil {
-			return err
+		i.Password.Password = models.AsymSecretStr(pass)
+		if totp := viper.GetString("pass.totp"); totp != "" {
+			i.TOTP, err = parseTOTP(totp)
+			if err != nil {
+				return err
+			}
 		}
-		return a.Flush()
+		return createItem(i)
 	},
 }
 
@@ -164,12 +176,18 @@ func init() {
 	passCmd.Flags().String("password", "", "Password (not recommended, use stdin)")
 	viper.BindPFlag("pass.password", passCmd.Flags().Lookup("password"))
 
+	passCmd.Flags().Bool("password-stdin", false, "Read the password from stdin")
+	viper.BindPFlag("pass.password-stdin", passCmd.Flags().Lookup("password-stdin"))
+
 	passCmd.Flags().String("site", "", "Site host name. eg. gmail.com, github.com")
 	viper.BindPFlag("pass.site", passCmd.Flags().Lookup("site"))
 
 	passCmd.Flags().String("url", "", "Site login url")
 	viper.BindPFlag("pass.url", passCmd.Flags().Lookup("url"))
 
+	passCmd.Flags().String("totp", "", "Attach a TOTP, otpauth:// URI or base32 secret")
+	viper.BindPFlag("pass.totp", passCmd.Flags().Lookup("totp"))
+
 	// cobra.MarkFlagRequired(passCmd.Flags(), "username")
 	// cobra.MarkFlagRequired(passCmd.Flags(), "site")
 }
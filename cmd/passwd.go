@@ -0,0 +1,90 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// passwdCmd represents the passwd command
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the master password",
+	Long: `Change the master password.
+
+The private keys are decrypted with the current password and written again
+encrypted with the new one. Items are not touched, they are encrypted with
+the public keys. The cached password is replaced with the new one.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	// Only the private key file is changed, the current password is asked
+	// for below
+	Annotations: map[string]string{skipBackend: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := viper.GetString(vkeys.PrivateKeysPath)
+
+		fmt.Print("Enter your current master password: ")
+		old, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if err != nil {
+			return fmt.Errorf("failed to read master password: %w", err)
+		}
+		fmt.Println()
+		viper.Set(vkeys.Password, string(old))
+		if _, err := config.ReadPrivateKeys(file); err != nil {
+			return fmt.Errorf("wrong master password: %w", err)
+		}
+
+		password, err := readNewPassword("new master password")
+		if err != nil {
+			return err
+		}
+		if len(password) == 0 {
+			return fmt.Errorf("master password can't be empty")
+		}
+
+		// Read the file again under the lock, a concurrent keys rotate may
+		// have changed it in the meantime
+		err = backend.WithLock(viper.GetString(vkeys.DatabasePath), func() error {
+			return config.ChangePassword(file, string(old), string(password))
+		})
+		if err != nil {
+			return err
+		}
+		if err := config.CachePassword(password); err != nil {
+			return fmt.Errorf("master password changed, but failed to update the cached password: %w", err)
+		}
+		fmt.Println("Master password changed.")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(passwdCmd)
+}
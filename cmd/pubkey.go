@@ -0,0 +1,170 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/encryption"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pubkeyCmd represents the pubkey command
+var pubkeyCmd = &cobra.Command{
+	Use:   "pubkey",
+	Short: "Manage the public keys items are encrypted for",
+}
+
+var pubkeyListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List public keys",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		keys, err := a.PublicKeys()
+		if err != nil {
+			return err
+		}
+		if viper.GetBool("pubkey.list.json") {
+			if keys == nil {
+				keys = []string{}
+			}
+			return json.NewEncoder(os.Stdout).Encode(keys)
+		}
+		for _, k := range keys {
+			fmt.Println(k)
+		}
+		return nil
+	},
+}
+
+var pubkeyAddCmd = &cobra.Command{
+	Use:   "add <public-key>...",
+	Short: "Add public keys and encrypt all items for them",
+	Long: `Add public keys and encrypt all items for them.
+
+Every item is encrypted again, so the owner of the matching private key
+can decrypt all of them.`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		if err := a.AddPublicKeys(args...); err != nil {
+			return err
+		}
+		if err := a.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Added %d public key(s), all items are encrypted again.\n", len(args))
+		return nil
+	},
+}
+
+var pubkeyRemoveCmd = &cobra.Command{
+	Use:   "remove <public-key>...",
+	Short: "Remove public keys and encrypt all items without them",
+	Long: `Remove public keys and encrypt all items without them.
+
+Every item is encrypted again, so the owner of the removed key loses access.
+Removing every key that belongs to your own private keys is refused unless
+--force is given, since you would lose access to the vault.`,
+	Aliases:      []string{"rm"},
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		if !viper.GetBool("pubkey.remove.force") {
+			if err := checkOwnKeyRemains(a, args); err != nil {
+				return err
+			}
+		}
+		if err := a.RemovePublicKeys(args...); err != nil {
+			return err
+		}
+		if err := a.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d public key(s), all items are encrypted again.\n", len(args))
+		return nil
+	},
+}
+
+// checkOwnKeyRemains returns an error if removing keys would leave no
+// public key matching one of our private keys.
+func checkOwnKeyRemains(a backend.Backend, removed []string) error {
+	own, err := encryption.PublicKeysOf(viper.GetStringSlice(vkeys.PrivateKeys)...)
+	if err != nil {
+		return err
+	}
+	keys, err := a.PublicKeys()
+	if err != nil {
+		return err
+	}
+	isRemoved := make(map[string]bool, len(removed))
+	for _, k := range removed {
+		isRemoved[k] = true
+	}
+	for _, k := range keys {
+		if isRemoved[k] {
+			continue
+		}
+		for _, o := range own {
+			if k == o {
+				return nil
+			}
+		}
+	}
+	return errors.New("refusing to remove the last public key of your own private keys, use --force to remove anyway")
+}
+
+func init() {
+	rootCmd.AddCommand(pubkeyCmd)
+	pubkeyCmd.AddCommand(pubkeyListCmd, pubkeyAddCmd, pubkeyRemoveCmd)
+
+	pubkeyListCmd.Flags().Bool("json", false, "Print as JSON array")
+	viper.BindPFlag("pubkey.list.json", pubkeyListCmd.Flags().Lookup("json"))
+
+	pubkeyRemoveCmd.Flags().Bool("force", false, "Remove even if you lose access to the vault")
+	viper.BindPFlag("pubkey.remove.force", pubkeyRemoveCmd.Flags().Lookup("force"))
+}
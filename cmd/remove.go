@@ -0,0 +1,129 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/manifoldco/promptui"
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// confirm asks a yes/no question, a non-nil error means the user declined.
+func confirm(label string) error {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err
+}
+
+// removeCmd represents the remove command
+var removeCmd = &cobra.Command{
+	Use:   "remove <id>...",
+	Short: "Move items to the trash",
+	Long: `Move items to the trash.
+
+Trashed items are hidden from list, select and get. They can be restored with
+"mypass trash restore" until they are purged after trash_retention (default
+30 days). Use --purge to remove the items permanently right away.`,
+	Aliases:      []string{"rm"},
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		// Look up every item first, so we don't remove anything if
+		// one of the ids is wrong.
+		var items []*models.Item
+		for _, arg := range args {
+			id, err := parseItemID(arg)
+			if err != nil {
+				return err
+			}
+			i, err := a.GetItemByID(id)
+			if err != nil {
+				return err
+			}
+			items = append(items, i)
+		}
+
+		purge := viper.GetBool("remove.purge")
+		if !viper.GetBool("remove.yes") {
+			for _, i := range items {
+				fmt.Println(i)
+			}
+			label := fmt.Sprintf("Move %d item(s) to the trash", len(items))
+			if purge {
+				label = fmt.Sprintf("Remove %d item(s) permanently", len(items))
+			}
+			err := confirm(label)
+			if errors.Is(err, promptui.ErrAbort) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, i := range items {
+			if purge {
+				removed, err := a.RemoveItemByID(i.ID)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Removed %s\n", removed)
+				continue
+			}
+			trashed, err := a.TrashItemByID(i.ID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Trashed %s\n", trashed)
+		}
+		if _, err := purgeExpired(a); err != nil {
+			return err
+		}
+		return a.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	viper.BindPFlag("remove.yes", removeCmd.Flags().Lookup("yes"))
+
+	removeCmd.Flags().Bool("purge", false, "Remove permanently instead of moving to the trash")
+	viper.BindPFlag("remove.purge", removeCmd.Flags().Lookup("purge"))
+}
@@ -22,29 +22,36 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
 	"os"
+	"os/exec"
+	"time"
 
 	"github.com/riadafridishibly/mypass/backend"
 	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/version"
 	"github.com/riadafridishibly/mypass/vkeys"
 	"github.com/spf13/cobra"
 	jww "github.com/spf13/jwalterweatherman"
 	"github.com/spf13/viper"
-	"golang.design/x/clipboard"
 )
 
 var cfgFile string
 
+// Commands annotated with skipBackend don't load the database
+const skipBackend = "skip_backend"
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "mypass",
-	Short: "A dead simple password manager",
+	Use:     "mypass",
+	Short:   "A dead simple password manager",
+	Version: version.Version,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		if viper.GetBool("verbose") {
 			jww.SetStdoutThreshold(jww.LevelDebug)
 		}
 		// If command is not init then load the database
-		if cmd.CalledAs() != "init" {
+		if cmd.CalledAs() != "init" && cmd.Annotations[skipBackend] == "" {
 			b, err := backend.Get()
 			if err != nil {
 				return err
@@ -57,13 +64,6 @@ var rootCmd = &cobra.Command{
 			jww.INFO.Println("loaded public keys: ", pubKeys)
 			viper.Set(vkeys.PublicKeys, pubKeys)
 		}
-		// TODO: we may not need to load the clipboard,
-		// if the command is not select
-		err := clipboard.Init()
-		if err != nil {
-			// jww.FATAL.Fatal("Failed to initialize keyboard: ", err)
-			return err
-		}
 		return nil
 	},
 }
@@ -72,6 +72,11 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		// Exit with the status of the program we ran, eg. ssh of connect
+		os.Exit(exitErr.ExitCode())
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -82,10 +87,35 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mypass.yaml)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "Verbose mode")
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	rootCmd.PersistentFlags().Duration("clipboard-timeout", 45*time.Second, "Clear copied secrets from the clipboard after this duration, 0 to keep them")
+	viper.BindPFlag(vkeys.ClipboardTimeout, rootCmd.PersistentFlags().Lookup("clipboard-timeout"))
+	viper.SetDefault(vkeys.Backups, 10)
+	viper.SetDefault(vkeys.PasswordHistory, 5)
+	viper.SetDefault(vkeys.TrashRetention, 30*24*time.Hour)
+	rootCmd.PersistentFlags().Duration("lock-timeout", 10*time.Second, "Wait this long for other mypass processes to release the database")
+	viper.BindPFlag(vkeys.LockTimeout, rootCmd.PersistentFlags().Lookup("lock-timeout"))
+}
+
+// skipDecryption is the PersistentPreRunE of commands which never read
+// secrets. The flag must be set before the database is loaded, otherwise
+// the JSON backend asks for the master password to decrypt every item.
+func skipDecryption(cmd *cobra.Command, args []string) error {
+	viper.Set(vkeys.SkipDecryption, true)
+	return rootCmd.PersistentPreRunE(cmd, args)
 }
 
 var DefaultConfigPath = config.ExpandWithHome("~/.mypass.yaml")
 
+// loadSecrets loads the master password and private keys, both are
+// required before reading any encrypted field of an item.
+func loadSecrets() error {
+	err := config.LoadCachedPassword()
+	if err != nil {
+		return err
+	}
+	return config.LoadPrivateKeys()
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
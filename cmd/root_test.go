@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/config"
+	"github.com/riadafridishibly/mypass/encryption"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+// testVault is a JSON vault in a temporary directory, its private key is
+// encrypted with the master password "test" which is not cached.
+type testVault struct {
+	cfgFile string
+	dbPath  string
+	id      *age.X25519Identity
+}
+
+func newTestVault(t *testing.T, items ...*models.Item) *testVault {
+	t.Helper()
+	encryption.ScryptWorkFactor = 10
+	t.Cleanup(func() {
+		encryption.ScryptWorkFactor = 18
+		viper.Set("__backend_object", nil)
+		viper.Set(vkeys.PublicKeys, nil)
+		viper.Set(vkeys.PrivateKeys, nil)
+		viper.Set(vkeys.Password, nil)
+		viper.Set(vkeys.SkipDecryption, false)
+	})
+	dir := t.TempDir()
+	v := &testVault{
+		cfgFile: filepath.Join(dir, "cfg.yaml"),
+		dbPath:  filepath.Join(dir, "db"),
+	}
+	var err error
+	v.id, err = age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal("Failed to create age x25519 identity:", err)
+	}
+	viper.Set(vkeys.PrivateKeys, []string{v.id.String()})
+	viper.Set(vkeys.Password, "test")
+	keysFile := filepath.Join(dir, "private_keys")
+	err = config.WritePrivateKeys(keysFile, &models.PrivateKeys{Keys: []models.SymSecretStr{models.SymSecretStr(v.id.String())}})
+	if err != nil {
+		t.Fatal("Failed to write private keys:", err)
+	}
+	b, err := backend.Open(backend.BackendJSON, v.dbPath)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	if err := b.AddPublicKeys(v.id.Recipient().String()); err != nil {
+		t.Fatal("Failed to add public key:", err)
+	}
+	for _, i := range items {
+		if _, err := b.CreateItem(i); err != nil {
+			t.Fatal("Failed to create item:", err)
+		}
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal("Failed to flush:", err)
+	}
+
+	cfg := "backend: json\n" +
+		"database: " + v.dbPath + "\n" +
+		"private_keys: " + keysFile + "\n" +
+		"cached_password: " + filepath.Join(dir, "cached_pass") + "\n"
+	if err := os.WriteFile(v.cfgFile, []byte(cfg), 0600); err != nil {
+		t.Fatal("Failed to write config:", err)
+	}
+	// Like a new process, nothing is loaded
+	viper.Set(vkeys.PublicKeys, nil)
+	viper.Set(vkeys.PrivateKeys, nil)
+	viper.Set(vkeys.Password, nil)
+	return v
+}
+
+// items reads the items of the vault with its private key.
+func (v *testVault) items(t *testing.T) []*models.Item {
+	t.Helper()
+	oldKeys, oldPassword := viper.Get(vkeys.PrivateKeys), viper.Get(vkeys.Password)
+	viper.Set(vkeys.PrivateKeys, []string{v.id.String()})
+	viper.Set(vkeys.Password, "test")
+	defer func() {
+		viper.Set(vkeys.PrivateKeys, oldKeys)
+		viper.Set(vkeys.Password, oldPassword)
+	}()
+	b, err := backend.Open(backend.BackendJSON, v.dbPath)
+	if err != nil {
+		t.Fatal("Failed to open backend:", err)
+	}
+	defer b.Close()
+	items, err := b.ListAllItems()
+	if err != nil {
+		t.Fatal("Failed to list items:", err)
+	}
+	return items
+}
+
+// run runs mypass with the vault's config and args, stdin is not a
+// terminal. It returns the standard output.
+func (v *testVault) run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	inR, inW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inR.Close()
+	io.WriteString(inW, stdin)
+	inW.Close()
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(outR)
+		out <- string(data)
+	}()
+
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = inR, outW
+	viper.Set("__backend_object", nil)
+	rootCmd.SetArgs(append([]string{"--config", v.cfgFile}, args...))
+	rootCmd.SetErr(io.Discard)
+	err = rootCmd.Execute()
+	rootCmd.SetErr(nil)
+	os.Stdin, os.Stdout = oldStdin, oldStdout
+	outW.Close()
+	return <-out, err
+}
@@ -29,10 +29,10 @@ import (
 	"github.com/riadafridishibly/mypass/backend"
 	"github.com/riadafridishibly/mypass/config"
 	"github.com/riadafridishibly/mypass/models"
-	"golang.design/x/clipboard"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
 )
 
 type cfg struct {
@@ -46,10 +46,9 @@ type itemWithConfig struct {
 
 // selectCmd represents the select command
 var selectCmd = &cobra.Command{
-	Use:     "select",
-	Short:   "Select a password from a the list",
-	Long:    `Search password or interactively select items here`,
-	Aliases: []string{"list"},
+	Use:   "select",
+	Short: "Select a password from a the list",
+	Long:  `Search password or interactively select items here`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		err := config.LoadCachedPassword()
 		if err != nil {
@@ -74,7 +73,7 @@ var selectCmd = &cobra.Command{
 			return err
 		}
 		var items []itemWithConfig
-		for _, i := range itemsRaw {
+		for _, i := range models.ActiveItems(itemsRaw) {
 			items = append(items, itemWithConfig{Cfg: c, Item: i})
 		}
 		templates := &promptui.SelectTemplates{
@@ -99,9 +98,48 @@ var selectCmd = &cobra.Command{
  {{"Host:" | faint}}      {{.SSH.Host}}
  {{"Port:" | faint}}      {{.SSH.Port}}
  {{"Username:" | faint}}  {{.SSH.Username}}
+{{- if .SSH.HasKey}}
+ {{"Key:" | faint}}       {{.SSH.Fingerprint}}
+{{- end}}
 {{- if .Cfg.ShowPassword}}
  {{"Password:" | faint}}  {{.SSH.Password}}
 {{end}}
+{{end}}
+{{- if .APIKey}}
+{{ "Type:" | faint }}	{{ "apikey" }}
+ {{"Service:" | faint}}  {{.APIKey.Service}}
+ {{"KeyID:" | faint}}    {{.APIKey.KeyID}}
+ {{"Scopes:" | faint}}   {{range $i, $s := .APIKey.Scopes}}{{if $i}}, {{end}}{{$s}}{{end}}
+{{- if .APIKey.ExpiresAt}}
+ {{"Expires:" | faint}}  {{.APIKey.ExpiresAt.Format "2006-01-02"}}
+{{- end}}
+{{- if .Cfg.ShowPassword}}
+ {{"Secret:" | faint}}   {{.APIKey.Secret}}
+{{- end}}
+{{end}}
+{{- if .Card}}
+{{ "Type:" | faint }}	{{ "card" }}
+ {{"Brand:" | faint}}       {{.Card.Brand}}
+ {{"Cardholder:" | faint}}  {{.Card.Cardholder}}
+ {{"Number:" | faint}}      {{.Card.MaskedNumber}}
+ {{"Expiry:" | faint}}      {{.Card.Expiry}}
+{{- if .Cfg.ShowPassword}}
+ {{"CVV:" | faint}}         {{.Card.CVV}}
+ {{"PIN:" | faint}}         {{.Card.PIN}}
+{{- end}}
+{{end}}
+{{- if .Note}}
+{{ "Type:" | faint }}	{{ "note" }}
+{{- if .Cfg.ShowPassword}}
+{{ .Note.Body }}
+{{- end}}
+{{end}}
+{{- if .TOTP}}
+{{- if not (or .Password .SSH)}}
+{{ "Type:" | faint }}	{{ "totp" }}
+{{- end}}
+ {{"Issuer:" | faint}}    {{.TOTP.Issuer}}
+ {{"Account:" | faint}}   {{.TOTP.Account}}
 {{end}}`,
 		}
 
@@ -126,12 +164,66 @@ var selectCmd = &cobra.Command{
 			fmt.Printf("Prompt failed %v\n", err)
 			return err
 		}
-		v, err := items[i].GetPassword()
+		item := items[i].Item
+		if item.APIKey != nil {
+			if err := copyToClipboard([]byte(item.APIKey.Secret)); err != nil {
+				return err
+			}
+			fmt.Printf("Secret copied for %q to clipboard.\n", item.APIKey.String())
+			return nil
+		}
+		if item.Card != nil {
+			if err := copyToClipboard([]byte(item.Card.Number)); err != nil {
+				return err
+			}
+			fmt.Printf("Card number copied for %q to clipboard.\n", item.Card.String())
+			return nil
+		}
+		if item.Note != nil {
+			if err := copyToClipboard([]byte(item.Note.Body)); err != nil {
+				return err
+			}
+			fmt.Printf("Note copied for %q to clipboard.\n", item.Title)
+			return nil
+		}
+		if s := item.SSH; s != nil && s.Password == "" && s.HasKey() {
+			pub, err := s.PublicKey()
+			if err != nil {
+				return err
+			}
+			if err := copyToClipboard(ssh.MarshalAuthorizedKey(pub)); err != nil {
+				return err
+			}
+			fmt.Printf("Public key copied for %q to clipboard.\n", s.String())
+			return nil
+		}
+		if item.Password != nil || item.SSH != nil {
+			v, err := item.GetPassword()
+			if err != nil {
+				return err
+			}
+			if err := copyToClipboard([]byte(v)); err != nil {
+				return err
+			}
+			fmt.Printf("Password copied for %q to clipboard.\n", item.InnerItemString())
+			if item.TOTP == nil {
+				return nil
+			}
+			// The code is generated after the password is used, so
+			// it doesn't expire in between.
+			next := promptui.Prompt{Label: "Press Enter to copy the TOTP code"}
+			if _, err := next.Run(); err != nil {
+				return nil
+			}
+		}
+		code, remaining, err := totpCode(item)
 		if err != nil {
 			return err
 		}
-		clipboard.Write(clipboard.FmtText, []byte(v))
-		fmt.Printf("Password copied for %q to clipboard.\n", items[i].InnerItemString())
+		if err := copyToClipboard([]byte(code)); err != nil {
+			return err
+		}
+		fmt.Printf("TOTP code copied for %q to clipboard, valid for %ds.\n", item.Title, int(remaining.Seconds()))
 		return nil
 	},
 }
@@ -26,53 +26,80 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
-	"github.com/riadafridishibly/mypass/backend"
 	"github.com/riadafridishibly/mypass/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/term"
 )
 
-var sshFields = FieldsWithConfig{
-	"Title": &FieldConfig{
-		Default:    "SSH Item",
-		ValidateFn: func(string) error { return nil },
-	},
-	"Namespace": &FieldConfig{
-		Default: "default",
-		ValidateFn: func(s string) error {
-			if s == "" {
-				return errors.New("namespace can't be empty")
-			}
-			return nil
+func newDefaultSSHItem() *models.Item {
+	return &models.Item{
+		Title:     "SSH Item",
+		Namespace: "default",
+		Type:      "ssh",
+		Meta: models.Meta{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
 		},
-	},
-	"Host": &FieldConfig{
-		ValidateFn: func(s string) error {
-			if s == "" {
-				return errors.New("host can't be empty")
-			}
-			return nil
+		SSH: &models.SSHItem{
+			Host:     "",
+			Port:     22,
+			Username: "root",
+			Password: "",
 		},
-	},
-	"Port": &FieldConfig{
-		Default: "22",
-		ValidateFn: func(s string) error {
-			_, err := strconv.ParseUint(s, 10, 16)
-			return err
+	}
+}
+
+func newSSHFieldsWithConfig(i *models.Item) FieldsWithConfig {
+	return FieldsWithConfig{
+		"Title": &FieldConfig{
+			Default:    i.Title,
+			ValidateFn: func(string) error { return nil },
 		},
-	},
-	"Username": &FieldConfig{
-		Default: "root",
-		ValidateFn: func(s string) error {
-			if s == "" {
-				return errors.New("username can't be empty")
-			}
-			return nil
+		"Namespace": &FieldConfig{
+			Default: i.Namespace,
+			ValidateFn: func(s string) error {
+				if s == "" {
+					return errors.New("namespace can't be empty")
+				}
+				return nil
+			},
 		},
-	},
-	// TODO: Maybe add password here as well?
+		"Host": &FieldConfig{
+			Default: i.SSH.Host,
+			ValidateFn: func(s string) error {
+				if s == "" {
+					return errors.New("host can't be empty")
+				}
+				return nil
+			},
+		},
+		"Port": &FieldConfig{
+			Default: strconv.FormatUint(uint64(i.SSH.Port), 10),
+			ValidateFn: func(s string) error {
+				_, err := strconv.ParseUint(s, 10, 16)
+				return err
+			},
+		},
+		"Username": &FieldConfig{
+			Default: i.SSH.Username,
+			ValidateFn: func(s string) error {
+				if s == "" {
+					return errors.New("username can't be empty")
+				}
+				return nil
+			},
+		},
+		"Password": &FieldConfig{
+			Mask:    true,
+			Default: string(i.SSH.Password),
+			ValidateFn: func(s string) error {
+				return nil
+			},
+		},
+	}
 }
 
 const sshDetailsTpl = `
@@ -83,56 +110,109 @@ const sshDetailsTpl = `
 {{ "Host:" | faint }}	{{ .Value.Host }}
 {{ "Port:" | faint }}	{{ .Value.Port }}`
 
+// readPrivateKey reads an OpenSSH private key file and checks that it can
+// be decrypted with passphrase, which is asked for if it's needed and
+// missing.
+func readPrivateKey(file, passphrase string) (key, pass models.AsymSecretStr, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", err
+	}
+	i := &models.SSHItem{PrivateKey: models.AsymSecretStr(data), Passphrase: models.AsymSecretStr(passphrase)}
+	_, err = i.ParsePrivateKey()
+	if errors.Is(err, models.ErrPassphraseMissing) && stdinIsTerminal() {
+		fmt.Printf("Enter passphrase for %s: ", file)
+		b, rerr := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if rerr != nil {
+			return "", "", fmt.Errorf("failed to read passphrase: %w", rerr)
+		}
+		i.Passphrase = models.AsymSecretStr(b)
+		_, err = i.ParsePrivateKey()
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", file, err)
+	}
+	return i.PrivateKey, i.Passphrase, nil
+}
+
 // sshCmd represents the ssh command
 var sshCmd = &cobra.Command{
-	Use:   "ssh",
-	Short: "A brief description of your command",
+	Use:          "ssh",
+	Short:        "Add ssh item",
+	SilenceUsage: true,
+	Example: `  mypass add ssh --host example.com --port 2222 --username admin
+  echo "$PASS" | mypass add ssh --host example.com --password-stdin
+  mypass add ssh --host example.com --key-file ~/.ssh/id_ed25519`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Only interactive is implemented!
-		v, err := Prompt(
-			sshFields,
-			sshDetailsTpl,
-		)
+		i := newDefaultSSHItem()
+		i.Namespace = viper.GetString("add.namespace")
+		i.SSH.Host = viper.GetString("ssh.host")
+		i.SSH.Port = uint16(viper.GetUint("ssh.port"))
+		if username := viper.GetString("ssh.username"); username != "" {
+			i.SSH.Username = username
+		}
+		if title := viper.GetString("add.title"); title != "" {
+			i.Title = title
+		} else if i.SSH.Host != "" {
+			i.Title = i.SSH.Host
+		}
+		if viper.GetBool("ssh.password-stdin") && viper.GetBool("ssh.passphrase-stdin") {
+			return errors.New("--password-stdin and --passphrase-stdin can't be used together")
+		}
+		pass, err := passwordFromFlags("ssh")
 		if err != nil {
 			return err
 		}
-		pass := v["Password"]
-		if pass == "" {
-			// try read password from stdin
-			fmt.Print("Enter password:")
-			data, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if file := viper.GetString("ssh.key-file"); file != "" {
+			passphrase, err := secretFromFlags("ssh", "passphrase")
 			if err != nil {
 				return err
 			}
-			fmt.Println()
-			pass = string(data)
-		}
-
-		toInt := func(s string) uint16 {
-			v, _ := strconv.ParseUint(s, 10, 16)
-			return uint16(v)
+			key, keyPass, err := readPrivateKey(file, passphrase)
+			if err != nil {
+				return err
+			}
+			if err := i.SSH.SetPrivateKey(key, keyPass); err != nil {
+				return err
+			}
 		}
 
-		p := &models.SSHItem{
-			Host:     v["Host"],
-			Port:     toInt(v["Port"]),
-			Username: v["Username"],
-			Password: models.AsymSecretStr(pass),
+		var missing []string
+		if i.SSH.Host == "" {
+			missing = append(missing, "--host")
 		}
-
-		a, err := backend.Get()
+		prompt, err := needPrompt("ssh", missing)
 		if err != nil {
 			return err
 		}
-		_, err = a.CreateItem(&models.Item{
-			Title:     v["Title"],
-			Namespace: v["Namespace"],
-			SSH:       p,
-		})
-		if err != nil {
-			return err
+		if prompt {
+			i.SSH.Password = models.AsymSecretStr(pass)
+			v, err := Prompt(newSSHFieldsWithConfig(i), sshDetailsTpl)
+			if err != nil {
+				return err
+			}
+			port, err := strconv.ParseUint(v["Port"], 10, 16)
+			if err != nil {
+				return err
+			}
+			i.Title = v["Title"]
+			i.Namespace = v["Namespace"]
+			i.SSH.Host = v["Host"]
+			i.SSH.Port = uint16(port)
+			i.SSH.Username = v["Username"]
+			pass = v["Password"]
 		}
-		return a.Flush()
+
+		// The password is optional with key authentication
+		if !i.SSH.HasKey() {
+			pass, err = ensurePassword(pass)
+			if err != nil {
+				return err
+			}
+		}
+		i.SSH.Password = models.AsymSecretStr(pass)
+		return createItem(i)
 	},
 }
 
@@ -145,9 +225,21 @@ func init() {
 	sshCmd.Flags().String("password", "", "Password (not recommended, use stdin)")
 	viper.BindPFlag("ssh.password", sshCmd.Flags().Lookup("password"))
 
+	sshCmd.Flags().Bool("password-stdin", false, "Read the password from stdin")
+	viper.BindPFlag("ssh.password-stdin", sshCmd.Flags().Lookup("password-stdin"))
+
 	sshCmd.Flags().String("host", "", "Site host name. eg. example.com")
 	viper.BindPFlag("ssh.host", sshCmd.Flags().Lookup("host"))
 
 	sshCmd.Flags().Uint16("port", 22, "Port")
 	viper.BindPFlag("ssh.port", sshCmd.Flags().Lookup("port"))
+
+	sshCmd.Flags().String("key-file", "", "Import the OpenSSH private key from this file")
+	viper.BindPFlag("ssh.key-file", sshCmd.Flags().Lookup("key-file"))
+
+	sshCmd.Flags().String("passphrase", "", "Passphrase of the private key (not recommended, use stdin)")
+	viper.BindPFlag("ssh.passphrase", sshCmd.Flags().Lookup("passphrase"))
+
+	sshCmd.Flags().Bool("passphrase-stdin", false, "Read the passphrase of the private key from stdin")
+	viper.BindPFlag("ssh.passphrase-stdin", sshCmd.Flags().Lookup("passphrase-stdin"))
 }
@@ -0,0 +1,141 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// dialAgent connects to the ssh agent listening on $SSH_AUTH_SOCK.
+func dialAgent() (agent.ExtendedAgent, net.Conn, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set, is ssh-agent running?")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to the ssh agent: %w", err)
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// addToAgent loads the private key of the ssh item into the agent, a
+// zero lifetime keeps it until the agent is stopped.
+func addToAgent(ag agent.Agent, i *models.Item, lifetime time.Duration, confirm bool) (ssh.PublicKey, error) {
+	if i.SSH == nil {
+		return nil, fmt.Errorf("item %d is not a ssh item", i.ID)
+	}
+	if !i.SSH.HasKey() {
+		return nil, fmt.Errorf("item %d has no private key", i.ID)
+	}
+	key, err := i.SSH.ParsePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := i.SSH.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	err = ag.Add(agent.AddedKey{
+		PrivateKey:       key,
+		Comment:          i.Namespace + "/" + i.Title,
+		LifetimeSecs:     uint32(lifetime.Seconds()),
+		ConfirmBeforeUse: confirm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add the key to the ssh agent: %w", err)
+	}
+	return pub, nil
+}
+
+// sshAddCmd represents the ssh-add command
+var sshAddCmd = &cobra.Command{
+	Use:   "ssh-add <id|title|namespace/title>",
+	Short: "Load the private key of a ssh item into the ssh agent",
+	Long: `Load the private key of a ssh item into the ssh agent.
+
+The agent is reached through $SSH_AUTH_SOCK. The key never touches the
+disk, it's decrypted and sent to the agent directly.`,
+	Example: `  mypass ssh-add prod-server
+  mypass ssh-add prod-server --lifetime 1h --confirm`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var lifetime time.Duration
+		if s := viper.GetString("ssh-add.lifetime"); s != "" {
+			var err error
+			lifetime, err = parseDuration(s)
+			if err != nil {
+				return err
+			}
+			if lifetime < time.Second {
+				return fmt.Errorf("lifetime %s is too short", s)
+			}
+		}
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		i, err := findItem(a, args[0])
+		if err != nil {
+			return err
+		}
+		ag, conn, err := dialAgent()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		pub, err := addToAgent(ag, i, lifetime, viper.GetBool("ssh-add.confirm"))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Identity added: %s/%s (%s)\n", i.Namespace, i.Title, ssh.FingerprintSHA256(pub))
+		if lifetime > 0 {
+			fmt.Printf("Lifetime set to %s\n", lifetime)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sshAddCmd)
+
+	sshAddCmd.Flags().StringP("lifetime", "t", "", "Remove the key from the agent after this duration, eg. 1h, 1d")
+	viper.BindPFlag("ssh-add.lifetime", sshAddCmd.Flags().Lookup("lifetime"))
+
+	sshAddCmd.Flags().BoolP("confirm", "c", false, "Ask the agent for confirmation before each use of the key")
+	viper.BindPFlag("ssh-add.confirm", sshAddCmd.Flags().Lookup("confirm"))
+}
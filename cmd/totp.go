@@ -0,0 +1,215 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/otp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// parseTOTP parses an otpauth:// URI or a plain base32 secret, which gets
+// the default parameters.
+func parseTOTP(s string) (*models.TOTPItem, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "otpauth:") {
+		k, err := otp.ParseURI(s)
+		if err != nil {
+			return nil, err
+		}
+		return models.NewTOTPItem(k), nil
+	}
+	k := otp.NewKey(s)
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	return models.NewTOTPItem(k), nil
+}
+
+func newDefaultTOTPItem() *models.Item {
+	return &models.Item{
+		Title:     "TOTP Item",
+		Namespace: "default",
+		Type:      models.ItemTOTP,
+		Meta: models.Meta{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		TOTP: &models.TOTPItem{},
+	}
+}
+
+func newTOTPFieldsWithConfig(i *models.Item) FieldsWithConfig {
+	return FieldsWithConfig{
+		"Title": &FieldConfig{
+			Default:    i.Title,
+			ValidateFn: func(string) error { return nil },
+		},
+		"Namespace": &FieldConfig{
+			Default: i.Namespace,
+			ValidateFn: func(s string) error {
+				if s == "" {
+					return errors.New("namespace can't be empty")
+				}
+				return nil
+			},
+		},
+		"Issuer": &FieldConfig{
+			Default:    i.TOTP.Issuer,
+			ValidateFn: func(string) error { return nil },
+		},
+		"Account": &FieldConfig{
+			Default:    i.TOTP.Account,
+			ValidateFn: func(string) error { return nil },
+		},
+		"Secret": &FieldConfig{
+			Mask:    true,
+			Default: string(i.TOTP.Secret),
+			ValidateFn: func(s string) error {
+				_, err := parseTOTP(s)
+				return err
+			},
+		},
+	}
+}
+
+const totpDetailsTpl = `
+--------- TOTP Credential ----------
+{{ "Title:" | faint }}	{{ .Value.Title }}
+{{ "Namespace:" | faint }}	{{ .Value.Namespace }}
+{{ "Issuer:" | faint }}	{{ .Value.Issuer }}
+{{ "Account:" | faint }}	{{ .Value.Account }}`
+
+// totpCmd represents the add totp command
+var totpCmd = &cobra.Command{
+	Use:   "totp",
+	Short: "Add TOTP item",
+	Long: `Add TOTP item.
+
+The secret is either an otpauth:// URI, as encoded in the QR codes, or the
+base32 secret. The other flags override the parameters of the URI.`,
+	SilenceUsage: true,
+	Example: `  mypass add totp --secret-stdin <<< 'otpauth://totp/GitHub:me?secret=JBSWY3DPEHPK3PXP&issuer=GitHub'
+  mypass add totp --issuer GitHub --account me --secret JBSWY3DPEHPK3PXP`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		i := newDefaultTOTPItem()
+		i.Namespace = viper.GetString("add.namespace")
+		secret, err := secretFromFlags("totp", "secret")
+		if err != nil {
+			return err
+		}
+		if secret != "" {
+			i.TOTP, err = parseTOTP(secret)
+			if err != nil {
+				return err
+			}
+		}
+		if cmd.Flags().Changed("issuer") {
+			i.TOTP.Issuer = viper.GetString("totp.issuer")
+		}
+		if cmd.Flags().Changed("account") {
+			i.TOTP.Account = viper.GetString("totp.account")
+		}
+		if cmd.Flags().Changed("algorithm") {
+			i.TOTP.Algorithm = strings.ToUpper(viper.GetString("totp.algorithm"))
+		}
+		if cmd.Flags().Changed("digits") {
+			i.TOTP.Digits = viper.GetInt("totp.digits")
+		}
+		if cmd.Flags().Changed("period") {
+			i.TOTP.Period = viper.GetInt("totp.period")
+		}
+		if title := viper.GetString("add.title"); title != "" {
+			i.Title = title
+		} else if i.TOTP.Issuer != "" {
+			i.Title = i.TOTP.Issuer
+		} else if i.TOTP.Account != "" {
+			i.Title = i.TOTP.Account
+		}
+
+		var missing []string
+		if secret == "" {
+			missing = append(missing, "--secret")
+		}
+		prompt, err := needPrompt("totp", missing)
+		if err != nil {
+			return err
+		}
+		if prompt {
+			v, err := Prompt(newTOTPFieldsWithConfig(i), totpDetailsTpl)
+			if err != nil {
+				return err
+			}
+			totp, err := parseTOTP(v["Secret"])
+			if err != nil {
+				return err
+			}
+			// The URI has the parameters, keep the ones of the flags otherwise
+			if !strings.HasPrefix(strings.TrimSpace(v["Secret"]), "otpauth:") {
+				totp.Algorithm, totp.Digits, totp.Period = i.TOTP.Algorithm, i.TOTP.Digits, i.TOTP.Period
+			}
+			if v["Issuer"] != "" {
+				totp.Issuer = v["Issuer"]
+			}
+			if v["Account"] != "" {
+				totp.Account = v["Account"]
+			}
+			i.Title = v["Title"]
+			i.Namespace = v["Namespace"]
+			i.TOTP = totp
+		}
+		if err := i.TOTP.Validate(); err != nil {
+			return err
+		}
+		return createItem(i)
+	},
+}
+
+func init() {
+	addCmd.AddCommand(totpCmd)
+
+	totpCmd.Flags().String("secret", "", "otpauth:// URI or base32 secret (not recommended, use stdin)")
+	viper.BindPFlag("totp.secret", totpCmd.Flags().Lookup("secret"))
+
+	totpCmd.Flags().Bool("secret-stdin", false, "Read the otpauth:// URI or secret from stdin")
+	viper.BindPFlag("totp.secret-stdin", totpCmd.Flags().Lookup("secret-stdin"))
+
+	totpCmd.Flags().String("issuer", "", "Issuer, eg. GitHub")
+	viper.BindPFlag("totp.issuer", totpCmd.Flags().Lookup("issuer"))
+
+	totpCmd.Flags().String("account", "", "Account name")
+	viper.BindPFlag("totp.account", totpCmd.Flags().Lookup("account"))
+
+	totpCmd.Flags().String("algorithm", otp.DefaultAlgorithm, "Hash algorithm: SHA1, SHA256, SHA512")
+	viper.BindPFlag("totp.algorithm", totpCmd.Flags().Lookup("algorithm"))
+
+	totpCmd.Flags().Int("digits", otp.DefaultDigits, "Number of digits of the codes")
+	viper.BindPFlag("totp.digits", totpCmd.Flags().Lookup("digits"))
+
+	totpCmd.Flags().Int("period", otp.DefaultPeriod, "Seconds each code is valid for")
+	viper.BindPFlag("totp.period", totpCmd.Flags().Lookup("period"))
+}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2023 Riad Afridi Shibly <riadafridishibly@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/riadafridishibly/mypass/backend"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func trashedItems(a backend.Backend) ([]*models.Item, error) {
+	items, err := a.ListAllItems()
+	if err != nil {
+		return nil, err
+	}
+	var out []*models.Item
+	for _, i := range items {
+		if i.IsTrashed() {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// purgeExpired permanently removes the items which are in the trash for
+// longer than trash_retention and returns their number.
+func purgeExpired(a backend.Backend) (int, error) {
+	retention := viper.GetDuration(vkeys.TrashRetention)
+	if retention <= 0 {
+		return 0, nil
+	}
+	items, err := trashedItems(a)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, i := range items {
+		if time.Since(*i.Meta.DeletedAt) < retention {
+			continue
+		}
+		removed, err := a.RemoveItemByID(i.ID)
+		if err != nil {
+			return n, err
+		}
+		fmt.Printf("Purged %s\n", removed)
+		n++
+	}
+	return n, nil
+}
+
+// parseTrashedItems looks up the items of ids, all of them must be in
+// the trash.
+func parseTrashedItems(a backend.Backend, ids []string) ([]*models.Item, error) {
+	var items []*models.Item
+	for _, arg := range ids {
+		id, err := parseItemID(arg)
+		if err != nil {
+			return nil, err
+		}
+		i, err := a.GetItemByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if !i.IsTrashed() {
+			return nil, fmt.Errorf("item %d is not in the trash", id)
+		}
+		items = append(items, i)
+	}
+	return items, nil
+}
+
+// trashCmd represents the trash command
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage removed items",
+	Long: `Manage removed items.
+
+Items removed with "mypass remove" are kept in the trash for trash_retention
+(default 30 days), after that they are purged permanently.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the items in the trash",
+	Aliases:      []string{"ls"},
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		n, err := purgeExpired(a)
+		if err != nil {
+			return err
+		}
+		items, err := trashedItems(a)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			if err := a.Flush(); err != nil {
+				return err
+			}
+		}
+		if len(items) == 0 {
+			fmt.Println("The trash is empty.")
+			return nil
+		}
+		retention := viper.GetDuration(vkeys.TrashRetention)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAMESPACE\tTITLE\tTYPE\tDELETED\tPURGED")
+		for _, i := range items {
+			purged := "never"
+			if retention > 0 {
+				purged = formatTime(i.Meta.DeletedAt.Add(retention))
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				i.ID, i.Namespace, i.Title, i.GetType(), formatTime(*i.Meta.DeletedAt), purged)
+		}
+		return w.Flush()
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:          "restore <id>...",
+	Short:        "Take items out of the trash",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		items, err := parseTrashedItems(a, args)
+		if err != nil {
+			return err
+		}
+		for _, i := range items {
+			restored, err := a.RestoreItemByID(i.ID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restored %s\n", restored)
+		}
+		if _, err := purgeExpired(a); err != nil {
+			return err
+		}
+		return a.Flush()
+	},
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:   "purge [<id>...]",
+	Short: "Remove items in the trash permanently",
+	Long: `Remove items in the trash permanently.
+
+Without any id the whole trash is emptied.`,
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSecrets()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := backend.Get()
+		if err != nil {
+			return err
+		}
+		var items []*models.Item
+		if len(args) > 0 {
+			items, err = parseTrashedItems(a, args)
+		} else {
+			items, err = trashedItems(a)
+		}
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Println("The trash is empty.")
+			return nil
+		}
+
+		if !viper.GetBool("trash.purge.yes") {
+			for _, i := range items {
+				fmt.Println(i)
+			}
+			err := confirm(fmt.Sprintf("Remove %d item(s) permanently", len(items)))
+			if errors.Is(err, promptui.ErrAbort) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, i := range items {
+			removed, err := a.RemoveItemByID(i.ID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %s\n", removed)
+		}
+		return a.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashPurgeCmd)
+
+	trashPurgeCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	viper.BindPFlag("trash.purge.yes", trashPurgeCmd.Flags().Lookup("yes"))
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/riadafridishibly/mypass/encryption"
 	"github.com/riadafridishibly/mypass/models"
@@ -60,6 +61,10 @@ func LoadCachedPassword() error {
 		}
 	}
 
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		// Stdin may carry other input, eg. of --password-stdin
+		return errors.New("master password is not cached and stdin is not a terminal, set it with the PASSWORD environment variable")
+	}
 	fmt.Printf("Enter your master password: ")
 	data, err = term.ReadPassword(int(os.Stdin.Fd()))
 	if err != nil {
@@ -137,29 +142,117 @@ func LoadPrivateKeys() error {
 	if privKeyPath == "" {
 		return fmt.Errorf("private keys not found, path: %q", privKeyPath)
 	}
-	f, err := os.Open(privKeyPath)
+	privKeys, err := ReadPrivateKeys(privKeyPath)
 	if err != nil {
 		return err
 	}
+	var privKeySlice []string
+	for _, key := range privKeys.Keys {
+		privKeySlice = append(privKeySlice, string(key))
+	}
+	viper.Set(vkeys.PrivateKeys, privKeySlice)
+	return nil
+}
+
+// ReadPrivateKeys reads and decrypts the private key file with the
+// master password in viper.
+func ReadPrivateKeys(file string) (*models.PrivateKeys, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
 	var privKeys models.PrivateKeys
 	err = json.NewDecoder(f).Decode(&privKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &privKeys, nil
+}
+
+// WritePrivateKeys encrypts the private keys with the master password in
+// viper and replaces file atomically.
+func WritePrivateKeys(file string, privKeys *models.PrivateKeys) error {
+	data, err := json.Marshal(privKeys)
 	if err != nil {
 		return err
 	}
-	var privKeySlice []string
-	for _, key := range privKeys.Keys {
-		privKeySlice = append(privKeySlice, string(key))
+	return WriteFileAtomic(file, data, 0600)
+}
+
+// ChangePassword encrypts the private key file again with newPassword,
+// nothing is changed if oldPassword is wrong. The password in viper is the
+// one the file is encrypted with afterwards.
+func ChangePassword(file, oldPassword, newPassword string) error {
+	viper.Set(vkeys.Password, oldPassword)
+	privKeys, err := ReadPrivateKeys(file)
+	if err != nil {
+		return fmt.Errorf("wrong master password: %w", err)
+	}
+	viper.Set(vkeys.Password, newPassword)
+	privKeys.Meta.UpdatedAt = time.Now()
+	if err := WritePrivateKeys(file, privKeys); err != nil {
+		viper.Set(vkeys.Password, oldPassword)
+		return err
 	}
-	viper.Set(vkeys.PrivateKeys, privKeySlice)
 	return nil
 }
 
-func LoadDatabase() (*models.Database, error) {
-	if err := LoadPrivateKeys(); err != nil {
-		return nil, err
+// CachePassword replaces the cached master password. If that fails the
+// stale cache is removed, so it can't be used anymore.
+func CachePassword(pass []byte) error {
+	p := viper.GetString(vkeys.CachedPassword)
+	if p == "" {
+		return nil
+	}
+	err := encryptCache(p+".rnd", p, pass)
+	if err != nil {
+		_ = os.Remove(p)
 	}
+	return err
+}
 
+// WriteFileAtomic writes data to a temporary file in the same directory,
+// syncs it and renames it over file, so readers either see the old or
+// the new content, never a partial write.
+func WriteFileAtomic(file string, data []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return err
+	}
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func LoadDatabase() (*models.Database, error) {
 	if db := viper.Get("db"); db != nil {
 		v, ok := db.(*models.Database)
 		if ok {
@@ -170,16 +263,147 @@ func LoadDatabase() (*models.Database, error) {
 	if dbPath == "" {
 		return nil, errors.New("database path is not defined")
 	}
-	var db models.Database
+	db, err := ReadDatabase(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	viper.Set(vkeys.PublicKeys, db.PublicKeys)
+	viper.Set("db", db)
+	return db, nil
+}
+
+// ReadDatabase reads and decrypts the JSON database at dbPath.
+func ReadDatabase(dbPath string) (*models.Database, error) {
+	f, err := ReadDatabaseFile(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return f.Database, nil
+}
+
+// ReadDatabaseFile reads and decrypts the JSON database at dbPath,
+// files of older format versions are upgraded to the current one.
+func ReadDatabaseFile(dbPath string) (*models.DatabaseFile, error) {
+	if !viper.GetBool(vkeys.SkipDecryption) {
+		if err := LoadPrivateKeys(); err != nil {
+			return nil, err
+		}
+	}
 	data, err := os.ReadFile(dbPath)
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(data, &db)
+	f, err := parseDatabaseFile(data)
 	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dbPath, err)
+	}
+	return f, nil
+}
+
+var (
+	ErrUnknownDatabase = errors.New("not a mypass database")
+	ErrDatabaseTooNew  = errors.New("database format is newer than this version of mypass")
+)
+
+// databaseUpgrades[v] converts a database file of version v to v+1.
+var databaseUpgrades = []func(data []byte) ([]byte, error){
+	// 0: the bare database without the envelope
+	func(data []byte) ([]byte, error) {
+		return json.Marshal(map[string]any{
+			"magic":    models.DatabaseMagic,
+			"version":  1,
+			"database": json.RawMessage(data),
+		})
+	},
+}
+
+func parseDatabaseFile(data []byte) (*models.DatabaseFile, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownDatabase, err)
+	}
+	var header struct {
+		Magic   string `json:"magic"`
+		Version int    `json:"version"`
+	}
+	if _, ok := probe["magic"]; ok {
+		if err := json.Unmarshal(data, &header); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnknownDatabase, err)
+		}
+		if header.Magic != models.DatabaseMagic {
+			return nil, fmt.Errorf("%w: unknown format %q", ErrUnknownDatabase, header.Magic)
+		}
+		// Version 0 is the bare database, it never had the envelope
+		if header.Version < 1 {
+			return nil, fmt.Errorf("%w: invalid version %d", ErrUnknownDatabase, header.Version)
+		}
+	} else {
+		// Files without the envelope only had these keys
+		for k := range probe {
+			if k != "public_keys" && k != "items" {
+				return nil, fmt.Errorf("%w: unexpected key %q", ErrUnknownDatabase, k)
+			}
+		}
+	}
+	if header.Version > models.DatabaseVersion {
+		return nil, fmt.Errorf("%w: file has version %d, supported up to %d, please upgrade mypass",
+			ErrDatabaseTooNew, header.Version, models.DatabaseVersion)
+	}
+	for v := header.Version; v < models.DatabaseVersion; v++ {
+		var err error
+		data, err = databaseUpgrades[v](data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade database from version %d: %w", v, err)
+		}
+	}
+	var f models.DatabaseFile
+	if err := json.Unmarshal(data, &f); err != nil {
 		return nil, err
 	}
-	viper.Set(vkeys.PublicKeys, db.PublicKeys)
-	viper.Set("db", &db)
-	return &db, nil
+	if f.Database == nil {
+		f.Database = &models.Database{}
+	}
+	return &f, nil
+}
+
+// UpdateConfigFile sets the top level keys of the yaml config file to
+// values, comments and other keys are kept as they are.
+func UpdateConfigFile(file string, values map[string]string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s is not a yaml mapping", file)
+	}
+	for key, value := range values {
+		found := false
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == key {
+				root.Content[i+1].SetString(value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			k := &yaml.Node{}
+			k.SetString(key)
+			v := &yaml.Node{}
+			v.SetString(value)
+			root.Content = append(root.Content, k, v)
+		}
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(file, out, 0644)
 }
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/riadafridishibly/mypass/encryption"
+	"github.com/riadafridishibly/mypass/models"
+	"github.com/riadafridishibly/mypass/vkeys"
+	"github.com/spf13/viper"
+)
+
+func TestParseDatabaseFile(t *testing.T) {
+	t.Run("legacy", func(t *testing.T) {
+		f, err := parseDatabaseFile([]byte(`{"public_keys":["age1abc"]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Magic != models.DatabaseMagic || f.Version != models.DatabaseVersion {
+			t.Errorf("got header %q %d", f.Magic, f.Version)
+		}
+		if len(f.Database.PublicKeys) != 1 || f.Database.PublicKeys[0] != "age1abc" {
+			t.Errorf("public keys not preserved: %v", f.Database.PublicKeys)
+		}
+	})
+	t.Run("current", func(t *testing.T) {
+		f, err := parseDatabaseFile([]byte(`{"magic":"mypass-database","version":1,"created_by":"v1.0.0","database":{}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.CreatedBy != "v1.0.0" || f.Database == nil {
+			t.Errorf("unexpected file %+v", f)
+		}
+	})
+	t.Run("newer", func(t *testing.T) {
+		_, err := parseDatabaseFile([]byte(`{"magic":"mypass-database","version":99,"database":{}}`))
+		if !errors.Is(err, ErrDatabaseTooNew) {
+			t.Errorf("expected ErrDatabaseTooNew, got %v", err)
+		}
+	})
+	for _, data := range []string{
+		`{"magic":"something-else","version":1}`,
+		`{"magic":"mypass-database","version":0,"database":{"public_keys":["age1abc"]}}`,
+		`{"magic":"mypass-database","version":-1,"database":{}}`,
+		`{"magic":"mypass-database","database":{}}`,
+		`{"foo":1}`,
+		`[1,2]`,
+		`not json`,
+	} {
+		if _, err := parseDatabaseFile([]byte(data)); !errors.Is(err, ErrUnknownDatabase) {
+			t.Errorf("%s: expected ErrUnknownDatabase, got %v", data, err)
+		}
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	defer viper.Set(vkeys.Password, nil)
+	encryption.ScryptWorkFactor = 10
+	defer func() { encryption.ScryptWorkFactor = 18 }()
+	file := filepath.Join(t.TempDir(), "private_keys")
+	keys := []models.SymSecretStr{"AGE-SECRET-KEY-1", "AGE-SECRET-KEY-2"}
+	viper.Set(vkeys.Password, "old")
+	if err := WritePrivateKeys(file, &models.PrivateKeys{Keys: keys}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ChangePassword(file, "wrong", "new"); err == nil {
+		t.Fatal("expected error for wrong password")
+	}
+	if err := ChangePassword(file, "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if viper.GetString(vkeys.Password) != "new" {
+		t.Errorf("password in viper is not updated")
+	}
+	privKeys, err := ReadPrivateKeys(file)
+	if err != nil {
+		t.Fatal("failed to read with the new password:", err)
+	}
+	if len(privKeys.Keys) != 2 || privKeys.Keys[0] != keys[0] || privKeys.Keys[1] != keys[1] {
+		t.Errorf("keys are changed: %v", privKeys.Keys)
+	}
+	viper.Set(vkeys.Password, "old")
+	if _, err := ReadPrivateKeys(file); err == nil {
+		t.Errorf("old password still decrypts the keys")
+	}
+}
@@ -80,11 +80,16 @@ func decrypt(ciphertext []byte, i ...age.Identity) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
+// ScryptWorkFactor is the log2 of the scrypt cost of password encryption,
+// tests lower it to run faster.
+var ScryptWorkFactor = 18
+
 func EncryptWithPassword(plaintext []byte, password string) ([]byte, error) {
 	r, err := age.NewScryptRecipient(password)
 	if err != nil {
 		return nil, err
 	}
+	r.SetWorkFactor(ScryptWorkFactor)
 	return encrypt(plaintext, r)
 }
 
@@ -95,3 +100,22 @@ func DecryptWithPassword(ciphertext []byte, password string) ([]byte, error) {
 	}
 	return decrypt(ciphertext, i)
 }
+
+// ValidatePublicKey reports whether pubKey is a valid age X25519 recipient.
+func ValidatePublicKey(pubKey string) error {
+	_, err := pubKeys2recipients(pubKey)
+	return err
+}
+
+// PublicKeysOf returns the public keys of the given private keys.
+func PublicKeysOf(privKeys ...string) ([]string, error) {
+	var out []string
+	for _, privKey := range privKeys {
+		identity, err := age.ParseX25519Identity(privKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		out = append(out, identity.Recipient().String())
+	}
+	return out, nil
+}
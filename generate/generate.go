@@ -0,0 +1,161 @@
+package generate
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	Lower   = "abcdefghijklmnopqrstuvwxyz"
+	Upper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits  = "0123456789"
+	Symbols = "!@#$%^&*()-_=+[]{};:,.<>/?~"
+
+	// Characters which are easy to confuse with each other
+	Ambiguous = "Il1O0o|`'\";:,."
+)
+
+type Options struct {
+	Length int
+
+	Lower   bool
+	Upper   bool
+	Digits  bool
+	Symbols bool
+
+	// Leave out the characters in Ambiguous
+	ExcludeAmbiguous bool
+
+	// Minimum number of characters from each class,
+	// ignored if the class is disabled
+	MinLower   int
+	MinUpper   int
+	MinDigits  int
+	MinSymbols int
+}
+
+var DefaultOptions = Options{
+	Length:     20,
+	Lower:      true,
+	Upper:      true,
+	Digits:     true,
+	Symbols:    true,
+	MinLower:   1,
+	MinUpper:   1,
+	MinDigits:  1,
+	MinSymbols: 1,
+}
+
+type class struct {
+	chars string
+	min   int
+}
+
+func (o *Options) classes() []class {
+	var out []class
+	add := func(enabled bool, chars string, min int) {
+		if !enabled {
+			return
+		}
+		if o.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(Ambiguous, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		out = append(out, class{chars: chars, min: min})
+	}
+	add(o.Lower, Lower, o.MinLower)
+	add(o.Upper, Upper, o.MinUpper)
+	add(o.Digits, Digits, o.MinDigits)
+	add(o.Symbols, Symbols, o.MinSymbols)
+	return out
+}
+
+func (o *Options) validate() ([]class, string, error) {
+	classes := o.classes()
+	if len(classes) == 0 {
+		return nil, "", errors.New("at least one character class is required")
+	}
+	if o.Length <= 0 {
+		return nil, "", errors.New("length must be positive")
+	}
+	var all strings.Builder
+	min := 0
+	for _, c := range classes {
+		if c.min < 0 {
+			return nil, "", errors.New("minimum per class can't be negative")
+		}
+		min += c.min
+		all.WriteString(c.chars)
+	}
+	if min > o.Length {
+		return nil, "", fmt.Errorf("length %d is less than the sum of minimums %d", o.Length, min)
+	}
+	return classes, all.String(), nil
+}
+
+// Password generates a random password with crypto/rand.
+func Password(o Options) (string, error) {
+	classes, all, err := o.validate()
+	if err != nil {
+		return "", err
+	}
+	out := make([]byte, 0, o.Length)
+	for _, c := range classes {
+		for i := 0; i < c.min; i++ {
+			b, err := pick(c.chars)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, b)
+		}
+	}
+	for len(out) < o.Length {
+		b, err := pick(all)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, b)
+	}
+	// Don't leave the required characters at the beginning
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out), nil
+}
+
+// Entropy estimates the entropy of passwords generated with o in bits.
+func Entropy(o Options) (float64, error) {
+	_, all, err := o.validate()
+	if err != nil {
+		return 0, err
+	}
+	return float64(o.Length) * math.Log2(float64(len(all))), nil
+}
+
+func pick(chars string) (byte, error) {
+	i, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read random data: %w", err)
+	}
+	return int(v.Int64()), nil
+}
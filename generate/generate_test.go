@@ -0,0 +1,88 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	o := DefaultOptions
+	o.Length = 8
+	o.MinDigits = 4
+	o.ExcludeAmbiguous = true
+	for n := 0; n < 100; n++ {
+		p, err := Password(o)
+		if err != nil {
+			t.Fatal("Failed to generate password:", err)
+		}
+		if len(p) != o.Length {
+			t.Fatalf("Expected length %d, found %q", o.Length, p)
+		}
+		if strings.ContainsAny(p, Ambiguous) {
+			t.Fatalf("Ambiguous characters in %q", p)
+		}
+		count := func(chars string) int {
+			c := 0
+			for _, r := range p {
+				if strings.ContainsRune(chars, r) {
+					c++
+				}
+			}
+			return c
+		}
+		if count(Digits) < 4 || count(Lower) < 1 || count(Upper) < 1 || count(Symbols) < 1 {
+			t.Fatalf("Minimum per class not satisfied: %q", p)
+		}
+	}
+}
+
+func TestPasswordInvalidOptions(t *testing.T) {
+	if _, err := Password(Options{Length: 10}); err == nil {
+		t.Fatal("Expected error without any character class")
+	}
+	o := DefaultOptions
+	o.Length = 3
+	if _, err := Password(o); err == nil {
+		t.Fatal("Expected error when minimums exceed length")
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	e, err := Entropy(Options{Length: 10, Digits: true})
+	if err != nil {
+		t.Fatal("Failed to estimate entropy:", err)
+	}
+	if e < 33.2 || e > 33.3 {
+		t.Fatal("Expected ~33.22 bits, found", e)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	if n := len(Wordlist()); n != 7776 {
+		t.Fatal("Expected 7776 words, found", n)
+	}
+	o := PassphraseOptions{Words: 5, Separator: ".", Capitalize: true, Digit: true}
+	p, err := Passphrase(o)
+	if err != nil {
+		t.Fatal("Failed to generate passphrase:", err)
+	}
+	parts := strings.Split(p, ".")
+	if len(parts) != 5 {
+		t.Fatalf("Expected 5 words, found %q", p)
+	}
+	if !strings.ContainsAny(p, Digits) {
+		t.Fatalf("Expected a digit in %q", p)
+	}
+	for _, w := range parts {
+		if w[0] < 'A' || w[0] > 'Z' {
+			t.Fatalf("Expected capitalized words, found %q", p)
+		}
+	}
+	e, err := PassphraseEntropy(PassphraseOptions{Words: 6})
+	if err != nil {
+		t.Fatal("Failed to estimate entropy:", err)
+	}
+	if e < 77.5 || e > 77.6 {
+		t.Fatal("Expected ~77.55 bits, found", e)
+	}
+}
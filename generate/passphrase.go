@@ -0,0 +1,92 @@
+package generate
+
+import (
+	_ "embed"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EFF's large wordlist, https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+var (
+	wordsOnce sync.Once
+	words     []string
+)
+
+// Wordlist returns the words used for passphrases.
+func Wordlist() []string {
+	wordsOnce.Do(func() {
+		for _, line := range strings.Split(effLargeWordlist, "\n") {
+			// Each line is "<dice rolls>\t<word>"
+			_, word, ok := strings.Cut(strings.TrimSpace(line), "\t")
+			if ok && word != "" {
+				words = append(words, word)
+			}
+		}
+	})
+	return words
+}
+
+type PassphraseOptions struct {
+	Words     int
+	Separator string
+	// Capitalize the first letter of every word
+	Capitalize bool
+	// Append a random digit to a random word
+	Digit bool
+}
+
+var DefaultPassphraseOptions = PassphraseOptions{
+	Words:     6,
+	Separator: "-",
+}
+
+// Passphrase generates a diceware passphrase with crypto/rand.
+func Passphrase(o PassphraseOptions) (string, error) {
+	if o.Words <= 0 {
+		return "", errors.New("number of words must be positive")
+	}
+	list := Wordlist()
+	out := make([]string, o.Words)
+	for i := range out {
+		n, err := randInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		out[i] = list[n]
+		if o.Capitalize {
+			out[i] = strings.ToUpper(out[i][:1]) + out[i][1:]
+		}
+	}
+	if o.Digit {
+		i, err := randInt(len(out))
+		if err != nil {
+			return "", err
+		}
+		d, err := randInt(10)
+		if err != nil {
+			return "", err
+		}
+		out[i] += strconv.Itoa(d)
+	}
+	return strings.Join(out, o.Separator), nil
+}
+
+// PassphraseEntropy estimates the entropy of passphrases generated
+// with o in bits.
+func PassphraseEntropy(o PassphraseOptions) (float64, error) {
+	if o.Words <= 0 {
+		return 0, errors.New("number of words must be positive")
+	}
+	bits := float64(o.Words) * math.Log2(float64(len(Wordlist())))
+	if o.Digit {
+		bits += math.Log2(10 * float64(o.Words))
+	}
+	return bits, nil
+}
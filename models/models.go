@@ -5,20 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 	"unicode"
 
+	"github.com/riadafridishibly/mypass/card"
 	"github.com/riadafridishibly/mypass/encryption"
+	"github.com/riadafridishibly/mypass/otp"
 	"github.com/riadafridishibly/mypass/vkeys"
 	"github.com/spf13/viper"
 
+	"golang.org/x/crypto/ssh"
 	"xorm.io/xorm/convert"
 )
 
 // TODO: these functionalities will be replaced by backend
 
 var (
-	ErrItemNotFound = errors.New("item not found")
+	ErrItemNotFound  = errors.New("item not found")
+	ErrAmbiguousItem = errors.New("ambiguous item")
+	// The private key of the ssh item is protected
+	ErrPassphraseMissing = errors.New("private key is protected, a passphrase is required")
 )
 
 type AsymSecretStr string
@@ -29,6 +36,10 @@ var (
 )
 
 func (asc AsymSecretStr) MarshalJSON() ([]byte, error) {
+	if viper.GetBool(vkeys.SkipDecryption) {
+		// The value is empty, we would overwrite the real secret
+		return nil, errors.New("secrets are not decrypted, refusing to encrypt")
+	}
 	keys := viper.GetStringSlice(vkeys.PublicKeys)
 	data, err := encryption.Encrypt([]byte(asc), keys...)
 	if err != nil {
@@ -38,6 +49,10 @@ func (asc AsymSecretStr) MarshalJSON() ([]byte, error) {
 }
 
 func (asc *AsymSecretStr) UnmarshalJSON(data []byte) error {
+	if viper.GetBool(vkeys.SkipDecryption) {
+		*asc = ""
+		return nil
+	}
 	var b []byte
 	err := json.Unmarshal(data, &b)
 	if err != nil {
@@ -88,21 +103,65 @@ type ItemType string
 const (
 	ItemPassword ItemType = "password"
 	ItemSSH      ItemType = "ssh"
+	ItemTOTP     ItemType = "totp"
+	ItemNote     ItemType = "note"
+	ItemCard     ItemType = "card"
+	ItemAPIKey   ItemType = "apikey"
 )
 
 type Meta struct {
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Set while the item is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// SetDefaults sets the missing timestamps to now, the ones of items
+// copied from elsewhere are kept.
+func (m *Meta) SetDefaults() {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if m.UpdatedAt.IsZero() {
+		m.UpdatedAt = m.CreatedAt
+	}
 }
 
 type PrivateKeys struct {
 	Meta Meta           `json:"meta,omitempty"`
 	Keys []SymSecretStr `json:"keys,omitempty"`
+	// Rotation is set while a key rotation is in progress, the new key
+	// is always the last one in Keys.
+	Rotation *KeyRotation `json:"rotation,omitempty"`
+}
+
+type KeyRotation struct {
+	StartedAt time.Time `json:"started_at,omitempty"`
+	// Retire the older keys once all items are encrypted for the new key
+	Retire bool `json:"retire,omitempty"`
+}
+
+const (
+	// DatabaseMagic identifies mypass JSON databases
+	DatabaseMagic = "mypass-database"
+	// DatabaseVersion is the current version of the JSON database format
+	DatabaseVersion = 1
+)
+
+// DatabaseFile is the on-disk format of the JSON database.
+type DatabaseFile struct {
+	Magic   string `json:"magic"`
+	Version int    `json:"version"`
+	// Version of mypass which created the file
+	CreatedBy string    `json:"created_by,omitempty"`
+	Database  *Database `json:"database"`
 }
 
 type Database struct {
 	PublicKeys []string `json:"public_keys,omitempty"`
 	Items      []*Item  `json:"items,omitempty"`
+	// Highest id ever given, ids of removed items are not reused
+	LastID int `json:"last_id,omitempty"`
 }
 
 func (db *Database) AddItem(i *Item) (*Item, error) {
@@ -113,18 +172,54 @@ func (db *Database) AddItem(i *Item) (*Item, error) {
 		return i, errors.New("title can't be empty")
 	}
 	if i.ID == 0 {
-		i.ID = len(db.Items) + 1
+		i.ID = db.nextID()
+	} else if _, err := db.FindItemByID(i.ID); err == nil {
+		return i, fmt.Errorf("item with id=%d already exists", i.ID)
+	}
+	if i.ID > db.LastID {
+		db.LastID = i.ID
 	}
-	i.Meta.CreatedAt = time.Now()
-	i.Meta.UpdatedAt = time.Now()
+	i.Meta.SetDefaults()
 	db.Items = append(db.Items, i)
 	return i, nil
 }
 
-// TODO: Change Item to some struct with pointer to detect
-// which fields to update
-func (db *Database) UpdateItem(id int, i *Item) (*Item, error) {
-	panic("not implemented")
+func (db *Database) nextID() int {
+	// Databases written before LastID was stored only have the items
+	max := db.LastID
+	for _, i := range db.Items {
+		if i.ID > max {
+			max = i.ID
+		}
+	}
+	return max + 1
+}
+
+func (db *Database) UpdateItem(id int, p *ItemPatch) (*Item, error) {
+	i, err := db.FindItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Apply(i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (db *Database) TrashItem(id int) (*Item, error) {
+	i, err := db.FindItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return i, i.Trash()
+}
+
+func (db *Database) RestoreItem(id int) (*Item, error) {
+	i, err := db.FindItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return i, i.Restore()
 }
 
 func (db *Database) FindItemByID(id int) (*Item, error) {
@@ -139,7 +234,6 @@ func (db *Database) FindItemByID(id int) (*Item, error) {
 func (db *Database) RemoveItem(id int) (*Item, error) {
 	for idx, i := range db.Items {
 		if i.ID == id {
-			// Maybe prompt before deleting?
 			removed := db.Items[idx]
 			db.Items = append(db.Items[:idx], db.Items[idx+1:]...)
 			return removed, nil
@@ -149,19 +243,24 @@ func (db *Database) RemoveItem(id int) (*Item, error) {
 }
 
 func (db *Database) Namespaces() []string {
-	l := make(map[string]struct{}, len(db.Items))
-	uniq := make([]string, 0, len(db.Items))
-	for _, i := range db.Items {
-		if _, ok := l[i.Namespace]; ok {
-			continue
-		}
-		uniq = append(uniq, i.Namespace)
-		l[i.Namespace] = struct{}{}
+	counts := NamespaceCounts(db.Items)
+	uniq := make([]string, 0, len(counts))
+	for ns := range counts {
+		uniq = append(uniq, ns)
 	}
 	sort.Strings(uniq)
 	return uniq
 }
 
+// NamespaceCounts returns the number of items in each namespace.
+func NamespaceCounts(items []*Item) map[string]int {
+	counts := make(map[string]int)
+	for _, i := range items {
+		counts[i.Namespace]++
+	}
+	return counts
+}
+
 type Namespace struct {
 	Meta  Meta    `json:"meta,omitempty"`
 	Items []*Item `json:"items,omitempty"`
@@ -186,9 +285,459 @@ type Item struct {
 	Title     string        `json:"title,omitempty"`
 	Namespace string        `json:"namespace,omitempty"`
 	Type      ItemType      `json:"type,omitempty"`
-	Meta      Meta          `json:"meta,omitempty" xorm:"-"`
+	Meta      Meta          `json:"meta,omitempty" xorm:"extends"`
 	Password  *PasswordItem `xorm:"text 'password'" json:"password,omitempty"`
 	SSH       *SSHItem      `xorm:"text 'ssh'" json:"ssh,omitempty"`
+	// A TOTP item on its own or attached to a password item
+	TOTP   *TOTPItem   `xorm:"text 'totp'" json:"totp,omitempty"`
+	Note   *NoteItem   `xorm:"text 'note'" json:"note,omitempty"`
+	Card   *CardItem   `xorm:"text 'card'" json:"card,omitempty"`
+	APIKey *APIKeyItem `xorm:"text 'api_key'" json:"api_key,omitempty"`
+	// Previous passwords, newest first
+	History PasswordHistory `xorm:"text 'history'" json:"history,omitempty"`
+}
+
+type HistoryEntry struct {
+	Password AsymSecretStr `json:"password,omitempty"`
+	// When the password was replaced
+	ReplacedAt time.Time `json:"replaced_at,omitempty"`
+}
+
+type PasswordHistory []*HistoryEntry
+
+// push returns a copy of the history with old on top and at most max
+// entries, the ones equal to cur are dropped.
+func (h PasswordHistory) push(old, cur AsymSecretStr, max int) PasswordHistory {
+	v := PasswordHistory{}
+	if old != "" && old != cur {
+		v = append(v, &HistoryEntry{Password: old, ReplacedAt: time.Now()})
+	}
+	for _, e := range h {
+		if e.Password != cur {
+			v = append(v, e)
+		}
+	}
+	if max < 0 {
+		max = 0
+	}
+	if len(v) > max {
+		v = v[:max]
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// FromDB implements convert.Conversion
+func (h *PasswordHistory) FromDB(data []byte) error {
+	var v PasswordHistory
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*h = v
+	return nil
+}
+
+// ToDB implements convert.Conversion
+func (h *PasswordHistory) ToDB() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+var _ convert.Conversion = (*PasswordHistory)(nil)
+
+// ItemPatch describes a partial update of an Item. Only the non-nil
+// fields are applied, everything else is left untouched.
+type ItemPatch struct {
+	Title     *string
+	Namespace *string
+	Password  *PasswordItemPatch
+	SSH       *SSHItemPatch
+	// Replaces the TOTP of the item, one with an empty secret removes it
+	TOTP   *TOTPItem
+	Note   *NoteItemPatch
+	Card   *CardItemPatch
+	APIKey *APIKeyItemPatch
+}
+
+type NoteItemPatch struct {
+	Body *AsymSecretStr
+}
+
+type APIKeyItemPatch struct {
+	Service *string
+	KeyID   *string
+	Secret  *AsymSecretStr
+	Scopes  *[]string
+	// The zero time removes the expiry
+	ExpiresAt *time.Time
+}
+
+type CardItemPatch struct {
+	Cardholder *string
+	Number     *AsymSecretStr
+	Expiry     *string
+	CVV        *AsymSecretStr
+	PIN        *AsymSecretStr
+}
+
+type PasswordItemPatch struct {
+	Username *string
+	SiteName *string
+	URL      *string
+	Password *AsymSecretStr
+}
+
+type SSHItemPatch struct {
+	Host       *string
+	Port       *uint16
+	Username   *string
+	Password   *AsymSecretStr
+	PrivateKey *AsymSecretStr
+	Passphrase *AsymSecretStr
+}
+
+// IsEmpty reports whether the patch changes nothing.
+func (p *ItemPatch) IsEmpty() bool {
+	if p == nil {
+		return true
+	}
+	if p.Title != nil || p.Namespace != nil || p.TOTP != nil {
+		return false
+	}
+	if pp := p.Password; pp != nil &&
+		(pp.Username != nil || pp.SiteName != nil || pp.URL != nil || pp.Password != nil) {
+		return false
+	}
+	if sp := p.SSH; sp != nil &&
+		(sp.Host != nil || sp.Port != nil || sp.Username != nil || sp.Password != nil ||
+			sp.PrivateKey != nil || sp.Passphrase != nil) {
+		return false
+	}
+	if np := p.Note; np != nil && np.Body != nil {
+		return false
+	}
+	if cp := p.Card; cp != nil &&
+		(cp.Cardholder != nil || cp.Number != nil || cp.Expiry != nil || cp.CVV != nil || cp.PIN != nil) {
+		return false
+	}
+	if ap := p.APIKey; ap != nil &&
+		(ap.Service != nil || ap.KeyID != nil || ap.Secret != nil || ap.Scopes != nil || ap.ExpiresAt != nil) {
+		return false
+	}
+	return true
+}
+
+// Apply updates i with the fields set in the patch and bumps
+// Meta.UpdatedAt. A replaced password is kept in the item's history.
+// On error i is left unmodified.
+func (p *ItemPatch) Apply(i *Item) error {
+	if p == nil {
+		return nil
+	}
+	v := *i
+	if p.Title != nil {
+		if *p.Title == "" {
+			return errors.New("title can't be empty")
+		}
+		v.Title = *p.Title
+	}
+	if p.Namespace != nil {
+		if *p.Namespace == "" {
+			return errors.New("namespace can't be empty")
+		}
+		v.Namespace = *p.Namespace
+	}
+	if pp := p.Password; pp != nil {
+		if i.Password == nil {
+			return fmt.Errorf("item %d is not a password item", i.ID)
+		}
+		inner := *i.Password
+		if pp.Username != nil {
+			inner.Username = *pp.Username
+		}
+		if pp.SiteName != nil {
+			inner.SiteName = *pp.SiteName
+		}
+		if pp.URL != nil {
+			inner.URL = *pp.URL
+		}
+		if pp.Password != nil {
+			inner.Password = *pp.Password
+			v.History = i.History.push(i.Password.Password, inner.Password, viper.GetInt(vkeys.PasswordHistory))
+		}
+		v.Password = &inner
+	}
+	if sp := p.SSH; sp != nil {
+		if i.SSH == nil {
+			return fmt.Errorf("item %d is not a ssh item", i.ID)
+		}
+		inner := *i.SSH
+		if sp.Host != nil {
+			if *sp.Host == "" {
+				return errors.New("host can't be empty")
+			}
+			inner.Host = *sp.Host
+		}
+		if sp.Port != nil {
+			inner.Port = *sp.Port
+		}
+		if sp.Username != nil {
+			inner.Username = *sp.Username
+		}
+		if sp.Password != nil {
+			inner.Password = *sp.Password
+			v.History = i.History.push(i.SSH.Password, inner.Password, viper.GetInt(vkeys.PasswordHistory))
+		}
+		if sp.PrivateKey != nil || sp.Passphrase != nil {
+			key, passphrase := inner.PrivateKey, inner.Passphrase
+			if sp.PrivateKey != nil {
+				key = *sp.PrivateKey
+			}
+			if sp.Passphrase != nil {
+				passphrase = *sp.Passphrase
+			}
+			if err := inner.SetPrivateKey(key, passphrase); err != nil {
+				return err
+			}
+		}
+		v.SSH = &inner
+	}
+	if np := p.Note; np != nil {
+		if i.Note == nil {
+			return fmt.Errorf("item %d is not a note item", i.ID)
+		}
+		inner := *i.Note
+		if np.Body != nil {
+			if *np.Body == "" {
+				return errors.New("note can't be empty")
+			}
+			inner.Body = *np.Body
+		}
+		v.Note = &inner
+	}
+	if cp := p.Card; cp != nil {
+		if i.Card == nil {
+			return fmt.Errorf("item %d is not a card item", i.ID)
+		}
+		inner := *i.Card
+		if cp.Cardholder != nil {
+			inner.Cardholder = *cp.Cardholder
+		}
+		if cp.Number != nil {
+			inner.Number = *cp.Number
+		}
+		if cp.Expiry != nil {
+			inner.Expiry = *cp.Expiry
+		}
+		if cp.CVV != nil {
+			inner.CVV = *cp.CVV
+		}
+		if cp.PIN != nil {
+			inner.PIN = *cp.PIN
+		}
+		if err := inner.Normalize(); err != nil {
+			return err
+		}
+		v.Card = &inner
+	}
+	if ap := p.APIKey; ap != nil {
+		if i.APIKey == nil {
+			return fmt.Errorf("item %d is not an api key item", i.ID)
+		}
+		inner := *i.APIKey
+		if ap.Service != nil {
+			if *ap.Service == "" {
+				return errors.New("service can't be empty")
+			}
+			inner.Service = *ap.Service
+		}
+		if ap.KeyID != nil {
+			inner.KeyID = *ap.KeyID
+		}
+		if ap.Secret != nil {
+			if *ap.Secret == "" {
+				return errors.New("secret can't be empty")
+			}
+			inner.Secret = *ap.Secret
+			v.History = i.History.push(i.APIKey.Secret, inner.Secret, viper.GetInt(vkeys.PasswordHistory))
+		}
+		if ap.Scopes != nil {
+			inner.Scopes = *ap.Scopes
+		}
+		if ap.ExpiresAt != nil {
+			inner.ExpiresAt = nil
+			if !ap.ExpiresAt.IsZero() {
+				t := *ap.ExpiresAt
+				inner.ExpiresAt = &t
+			}
+		}
+		v.APIKey = &inner
+	}
+	if p.TOTP != nil {
+		switch {
+		case p.TOTP.Secret != "":
+			if err := p.TOTP.Validate(); err != nil {
+				return err
+			}
+			totp := *p.TOTP
+			v.TOTP = &totp
+		case i.GetType() == ItemTOTP:
+			return errors.New("secret can't be empty")
+		default:
+			v.TOTP = nil
+		}
+	}
+	v.Meta.UpdatedAt = time.Now()
+	*i = v
+	return nil
+}
+
+// The plain* types shadow the secret fields with plain strings, so
+// they are marshalled without encryption.
+type plainItem struct {
+	*Item
+	Password *plainPasswordItem  `json:"password,omitempty"`
+	SSH      *plainSSHItem       `json:"ssh,omitempty"`
+	TOTP     *plainTOTPItem      `json:"totp,omitempty"`
+	Note     *plainNoteItem      `json:"note,omitempty"`
+	Card     *plainCardItem      `json:"card,omitempty"`
+	APIKey   *plainAPIKeyItem    `json:"api_key,omitempty"`
+	History  []plainHistoryEntry `json:"history,omitempty"`
+}
+
+type plainTOTPItem struct {
+	*TOTPItem
+	Secret string `json:"secret,omitempty"`
+}
+
+type plainNoteItem struct {
+	*NoteItem
+	Body string `json:"body,omitempty"`
+}
+
+type plainCardItem struct {
+	*CardItem
+	Number string `json:"number,omitempty"`
+	CVV    string `json:"cvv,omitempty"`
+	PIN    string `json:"pin,omitempty"`
+}
+
+type plainAPIKeyItem struct {
+	*APIKeyItem
+	Secret string `json:"secret,omitempty"`
+}
+
+type plainHistoryEntry struct {
+	*HistoryEntry
+	Password string `json:"password,omitempty"`
+}
+
+type plainPasswordItem struct {
+	*PasswordItem
+	Password string `json:"password,omitempty"`
+}
+
+type plainSSHItem struct {
+	*SSHItem
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// MarshalPlainJSON marshals the item with all the secrets in plain text.
+func (i *Item) MarshalPlainJSON() ([]byte, error) {
+	v := plainItem{Item: i}
+	if i.Password != nil {
+		v.Password = &plainPasswordItem{PasswordItem: i.Password, Password: string(i.Password.Password)}
+	}
+	if i.SSH != nil {
+		v.SSH = &plainSSHItem{
+			SSHItem:    i.SSH,
+			Password:   string(i.SSH.Password),
+			PrivateKey: string(i.SSH.PrivateKey),
+			Passphrase: string(i.SSH.Passphrase),
+		}
+	}
+	if i.TOTP != nil {
+		v.TOTP = &plainTOTPItem{TOTPItem: i.TOTP, Secret: string(i.TOTP.Secret)}
+	}
+	if i.Note != nil {
+		v.Note = &plainNoteItem{NoteItem: i.Note, Body: string(i.Note.Body)}
+	}
+	if c := i.Card; c != nil {
+		v.Card = &plainCardItem{CardItem: c, Number: string(c.Number), CVV: string(c.CVV), PIN: string(c.PIN)}
+	}
+	if i.APIKey != nil {
+		v.APIKey = &plainAPIKeyItem{APIKeyItem: i.APIKey, Secret: string(i.APIKey.Secret)}
+	}
+	for _, e := range i.History {
+		v.History = append(v.History, plainHistoryEntry{HistoryEntry: e, Password: string(e.Password)})
+	}
+	return json.Marshal(v)
+}
+
+// IsTrashed reports whether the item is in the trash.
+func (i *Item) IsTrashed() bool {
+	return i.Meta.DeletedAt != nil
+}
+
+// Trash moves the item to the trash.
+func (i *Item) Trash() error {
+	if i.IsTrashed() {
+		return fmt.Errorf("item %d is already in the trash", i.ID)
+	}
+	now := time.Now()
+	i.Meta.DeletedAt = &now
+	return nil
+}
+
+// Restore takes the item out of the trash.
+func (i *Item) Restore() error {
+	if !i.IsTrashed() {
+		return fmt.Errorf("item %d is not in the trash", i.ID)
+	}
+	i.Meta.DeletedAt = nil
+	return nil
+}
+
+// ActiveItems returns the items which are not in the trash.
+func ActiveItems(items []*Item) []*Item {
+	var out []*Item
+	for _, i := range items {
+		if !i.IsTrashed() {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// GetType returns the type of the item, items created without
+// a type get it from their content.
+func (i *Item) GetType() ItemType {
+	if i.Type != "" {
+		return i.Type
+	}
+	if i.Password != nil {
+		return ItemPassword
+	}
+	if i.SSH != nil {
+		return ItemSSH
+	}
+	if i.TOTP != nil {
+		return ItemTOTP
+	}
+	if i.Note != nil {
+		return ItemNote
+	}
+	if i.Card != nil {
+		return ItemCard
+	}
+	if i.APIKey != nil {
+		return ItemAPIKey
+	}
+	return ""
 }
 
 func (i *Item) InnerItemString() string {
@@ -198,7 +747,31 @@ func (i *Item) InnerItemString() string {
 	if i.SSH != nil {
 		return i.SSH.String()
 	}
-	panic("internal error: both are null")
+	if i.TOTP != nil {
+		return i.TOTP.String()
+	}
+	if i.Note != nil {
+		return i.Note.String()
+	}
+	if i.Card != nil {
+		return i.Card.String()
+	}
+	if i.APIKey != nil {
+		return i.APIKey.String()
+	}
+	panic("internal error: all are null")
+}
+
+// ExpiresAt returns when the item expires, ok is false if it doesn't.
+func (i *Item) ExpiresAt() (t time.Time, ok bool) {
+	if i.APIKey != nil && i.APIKey.ExpiresAt != nil {
+		return *i.APIKey.ExpiresAt, true
+	}
+	if i.Card != nil && i.Card.Expiry != "" {
+		t, err := card.ParseExpiry(i.Card.Expiry)
+		return t, err == nil
+	}
+	return time.Time{}, false
 }
 
 func (i *Item) GetPassword() (string, error) {
@@ -243,6 +816,18 @@ func (i Item) String() string {
 	if i.SSH != nil {
 		args = append(args, i.SSH)
 	}
+	if i.TOTP != nil && i.Password == nil && i.SSH == nil {
+		args = append(args, i.TOTP)
+	}
+	if i.Note != nil {
+		args = append(args, i.Note)
+	}
+	if i.Card != nil {
+		args = append(args, i.Card)
+	}
+	if i.APIKey != nil {
+		args = append(args, i.APIKey)
+	}
 	return fmt.Sprint(args...)
 }
 
@@ -280,6 +865,116 @@ type SSHItem struct {
 	Port     uint16        `json:"port,omitempty"`
 	Username string        `json:"username,omitempty"`
 	Password AsymSecretStr `json:"password,omitempty"`
+	// OpenSSH private key as it's in the key file, if it's protected
+	// Passphrase is needed to use it.
+	PrivateKey AsymSecretStr `json:"private_key,omitempty"`
+	Passphrase AsymSecretStr `json:"passphrase,omitempty"`
+	// Public key of PrivateKey in the authorized_keys format, it's not
+	// encrypted so the key can be offered without decrypting the item.
+	AuthorizedKey string `json:"authorized_key,omitempty"`
+	// A private key is stored, set even if it's not decrypted
+	keyStored bool
+}
+
+func (s *SSHItem) UnmarshalJSON(data []byte) error {
+	type plain SSHItem
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	var stored struct {
+		PrivateKey json.RawMessage `json:"private_key"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	s.keyStored = len(stored.PrivateKey) > 0
+	// Items saved before the public key was stored get it once they're
+	// decrypted, it's written with the next save
+	if s.AuthorizedKey == "" && s.HasKey() {
+		if pub, err := s.storedPublicKey(); err == nil {
+			s.AuthorizedKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+		}
+	}
+	return nil
+}
+
+// MissingAuthorizedKey reports whether a private key is stored without
+// its public key, the secrets don't need to be decrypted.
+func (s *SSHItem) MissingAuthorizedKey() bool {
+	return s.AuthorizedKey == "" && (s.keyStored || s.HasKey())
+}
+
+// storedPublicKey returns the public key of the private key, it's read
+// without the passphrase from keys of the OpenSSH format.
+func (s *SSHItem) storedPublicKey() (ssh.PublicKey, error) {
+	_, err := ssh.ParsePrivateKey([]byte(s.PrivateKey))
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && missing.PublicKey != nil {
+		return missing.PublicKey, nil
+	}
+	return s.PublicKey()
+}
+
+// SetPrivateKey sets the private key and its passphrase after checking
+// them, an empty key removes the key.
+func (s *SSHItem) SetPrivateKey(key, passphrase AsymSecretStr) error {
+	if key == "" {
+		s.PrivateKey, s.Passphrase, s.AuthorizedKey = "", "", ""
+		return nil
+	}
+	v := SSHItem{PrivateKey: key, Passphrase: passphrase}
+	pub, err := v.PublicKey()
+	if err != nil {
+		return err
+	}
+	s.PrivateKey, s.Passphrase = key, passphrase
+	s.AuthorizedKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	return nil
+}
+
+// HasKey reports whether the item carries a private key.
+func (s *SSHItem) HasKey() bool {
+	return s.PrivateKey != ""
+}
+
+// ParsePrivateKey decodes the private key, see ssh.ParseRawPrivateKey for
+// the returned types.
+func (s *SSHItem) ParsePrivateKey() (any, error) {
+	if !s.HasKey() {
+		return nil, errors.New("ssh item has no private key")
+	}
+	if s.Passphrase != "" {
+		return ssh.ParseRawPrivateKeyWithPassphrase([]byte(s.PrivateKey), []byte(s.Passphrase))
+	}
+	key, err := ssh.ParseRawPrivateKey([]byte(s.PrivateKey))
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, ErrPassphraseMissing
+	}
+	return key, err
+}
+
+// PublicKey returns the public key of the private key.
+func (s *SSHItem) PublicKey() (ssh.PublicKey, error) {
+	key, err := s.ParsePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return signer.PublicKey(), nil
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key, or an empty
+// string if the item has no usable key.
+func (s *SSHItem) Fingerprint() string {
+	pub, err := s.PublicKey()
+	if err != nil {
+		return ""
+	}
+	return ssh.FingerprintSHA256(pub)
 }
 
 // FromDB implements convert.Conversion
@@ -303,3 +998,213 @@ var _ convert.Conversion = (*SSHItem)(nil)
 func (p SSHItem) String() string {
 	return fmt.Sprintf("ssh -p %d %s@%s", p.Port, p.Username, p.Host)
 }
+
+type TOTPItem struct {
+	Issuer  string `json:"issuer,omitempty"`
+	Account string `json:"account,omitempty"`
+	// Base32 encoded shared secret
+	Secret    AsymSecretStr `json:"secret,omitempty"`
+	Algorithm string        `json:"algorithm,omitempty"`
+	Digits    int           `json:"digits,omitempty"`
+	Period    int           `json:"period,omitempty"`
+}
+
+func NewTOTPItem(k *otp.Key) *TOTPItem {
+	return &TOTPItem{
+		Issuer:    k.Issuer,
+		Account:   k.Account,
+		Secret:    AsymSecretStr(k.Secret),
+		Algorithm: k.Algorithm,
+		Digits:    k.Digits,
+		Period:    k.Period,
+	}
+}
+
+// Key returns the generator of the item, missing parameters get the
+// defaults.
+func (t *TOTPItem) Key() *otp.Key {
+	k := otp.NewKey(string(t.Secret))
+	k.Issuer = t.Issuer
+	k.Account = t.Account
+	if t.Algorithm != "" {
+		k.Algorithm = t.Algorithm
+	}
+	if t.Digits != 0 {
+		k.Digits = t.Digits
+	}
+	if t.Period != 0 {
+		k.Period = t.Period
+	}
+	return k
+}
+
+func (t *TOTPItem) Validate() error {
+	return t.Key().Validate()
+}
+
+// Code returns the code at time now and how long it stays valid.
+func (t *TOTPItem) Code(now time.Time) (string, time.Duration, error) {
+	return t.Key().Code(now)
+}
+
+// FromDB implements convert.Conversion
+func (t *TOTPItem) FromDB(data []byte) error {
+	var v TOTPItem
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// ToDB implements convert.Conversion
+func (t *TOTPItem) ToDB() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+var _ convert.Conversion = (*TOTPItem)(nil)
+
+func (t TOTPItem) String() string {
+	return fmt.Sprintf("totp issuer=%s account=%s", t.Issuer, t.Account)
+}
+
+type NoteItem struct {
+	Body AsymSecretStr `json:"body,omitempty"`
+}
+
+// FromDB implements convert.Conversion
+func (n *NoteItem) FromDB(data []byte) error {
+	var v NoteItem
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*n = v
+	return nil
+}
+
+// ToDB implements convert.Conversion
+func (n *NoteItem) ToDB() ([]byte, error) {
+	return json.Marshal(n)
+}
+
+var _ convert.Conversion = (*NoteItem)(nil)
+
+// String doesn't show the body, it's printed in listings.
+func (n NoteItem) String() string {
+	if n.Body == "" {
+		// Not decrypted
+		return "note"
+	}
+	lines := strings.Count(strings.TrimRight(string(n.Body), "\n"), "\n") + 1
+	return fmt.Sprintf("note lines=%d", lines)
+}
+
+type CardItem struct {
+	Cardholder string `json:"cardholder,omitempty"`
+	// Detected from the number
+	Brand  string        `json:"brand,omitempty"`
+	Number AsymSecretStr `json:"number,omitempty"`
+	// MM/YY
+	Expiry string        `json:"expiry,omitempty"`
+	CVV    AsymSecretStr `json:"cvv,omitempty"`
+	PIN    AsymSecretStr `json:"pin,omitempty"`
+}
+
+// Normalize validates the card and brings the number and the expiry to
+// their canonical form, the brand is detected from the number.
+func (c *CardItem) Normalize() error {
+	number := card.Normalize(string(c.Number))
+	if err := card.ValidateNumber(number); err != nil {
+		return err
+	}
+	c.Number = AsymSecretStr(number)
+	c.Brand = card.Brand(number)
+	if c.Expiry != "" {
+		t, err := card.ParseExpiry(c.Expiry)
+		if err != nil {
+			return err
+		}
+		c.Expiry = card.FormatExpiry(t)
+	}
+	if c.CVV != "" {
+		if err := card.ValidateCVV(string(c.CVV), c.Brand); err != nil {
+			return err
+		}
+	}
+	return card.ValidatePIN(string(c.PIN))
+}
+
+// MaskedNumber returns the number with all but the last four digits hidden.
+func (c *CardItem) MaskedNumber() string {
+	return card.Mask(string(c.Number))
+}
+
+// FromDB implements convert.Conversion
+func (c *CardItem) FromDB(data []byte) error {
+	var v CardItem
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// ToDB implements convert.Conversion
+func (c *CardItem) ToDB() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+var _ convert.Conversion = (*CardItem)(nil)
+
+func (c CardItem) String() string {
+	brand := c.Brand
+	if brand == "" {
+		brand = "card"
+	}
+	if c.Number == "" {
+		// Not decrypted
+		return fmt.Sprintf("%s exp=%s", brand, c.Expiry)
+	}
+	return fmt.Sprintf("%s %s exp=%s", brand, c.MaskedNumber(), c.Expiry)
+}
+
+type APIKeyItem struct {
+	Service string        `json:"service,omitempty"`
+	KeyID   string        `json:"key_id,omitempty"`
+	Secret  AsymSecretStr `json:"secret,omitempty"`
+	Scopes  []string      `json:"scopes,omitempty"`
+	// Not set for keys which don't expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// FromDB implements convert.Conversion
+func (a *APIKeyItem) FromDB(data []byte) error {
+	var v APIKeyItem
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// ToDB implements convert.Conversion
+func (a *APIKeyItem) ToDB() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+var _ convert.Conversion = (*APIKeyItem)(nil)
+
+func (a APIKeyItem) String() string {
+	s := fmt.Sprintf("service=%s", a.Service)
+	if a.KeyID != "" {
+		s += fmt.Sprintf(" key_id=%s", a.KeyID)
+	}
+	if a.ExpiresAt != nil {
+		s += fmt.Sprintf(" expires=%s", a.ExpiresAt.Local().Format("2006-01-02"))
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SHA1   = "SHA1"
+	SHA256 = "SHA256"
+	SHA512 = "SHA512"
+
+	DefaultAlgorithm = SHA1
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+)
+
+// Key holds the parameters of a TOTP generator.
+type Key struct {
+	Issuer  string
+	Account string
+	// Base32 encoded shared secret
+	Secret    string
+	Algorithm string
+	Digits    int
+	// Seconds each code is valid for
+	Period int
+}
+
+// NewKey returns a key for secret with the default parameters.
+func NewKey(secret string) *Key {
+	return &Key{
+		Secret:    secret,
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+}
+
+// ParseURI parses a key from an otpauth://totp/ URI as described in
+// https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func ParseURI(uri string) (*Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("unsupported uri scheme %q, expected otpauth", u.Scheme)
+	}
+	if u.Host != "totp" {
+		return nil, fmt.Errorf("unsupported otp type %q, only totp is supported", u.Host)
+	}
+	q := u.Query()
+	k := NewKey(q.Get("secret"))
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		k.Issuer = strings.TrimSpace(issuer)
+		k.Account = strings.TrimSpace(account)
+	} else {
+		k.Account = label
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		k.Issuer = issuer
+	}
+	if v := q.Get("algorithm"); v != "" {
+		k.Algorithm = strings.ToUpper(v)
+	}
+	if v := q.Get("digits"); v != "" {
+		k.Digits, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid digits %q", v)
+		}
+	}
+	if v := q.Get("period"); v != "" {
+		k.Period, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid period %q", v)
+		}
+	}
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Validate checks the parameters of the key.
+func (k *Key) Validate() error {
+	if k.Secret == "" {
+		return errors.New("secret can't be empty")
+	}
+	if _, err := decodeSecret(k.Secret); err != nil {
+		return err
+	}
+	if _, err := newHash(k.Algorithm); err != nil {
+		return err
+	}
+	if k.Digits < 6 || k.Digits > 10 {
+		return fmt.Errorf("digits must be between 6 and 10, got %d", k.Digits)
+	}
+	if k.Period <= 0 {
+		return fmt.Errorf("period must be positive, got %d", k.Period)
+	}
+	return nil
+}
+
+// Code returns the code at time t and how long it stays valid.
+func (k *Key) Code(t time.Time) (string, time.Duration, error) {
+	secret, err := decodeSecret(k.Secret)
+	if err != nil {
+		return "", 0, err
+	}
+	h, err := newHash(k.Algorithm)
+	if err != nil {
+		return "", 0, err
+	}
+	if k.Digits < 6 || k.Digits > 10 || k.Period <= 0 {
+		return "", 0, errors.New("invalid digits or period")
+	}
+	period := int64(k.Period)
+	counter := t.Unix() / period
+	remaining := time.Duration(period-t.Unix()%period) * time.Second
+	return hotp(h, secret, uint64(counter), k.Digits), remaining, nil
+}
+
+// hotp implements RFC 4226.
+func hotp(h func() hash.Hash, secret []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(h, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	v := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+	mod := uint64(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, v%mod)
+}
+
+func newHash(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToUpper(algorithm) {
+	case SHA1, "":
+		return sha1.New, nil
+	case SHA256:
+		return sha256.New, nil
+	case SHA512:
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q, use one of SHA1, SHA256, SHA512", algorithm)
+}
+
+// decodeSecret decodes the base32 secret, spaces, case and the
+// padding are ignored.
+func decodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	s = strings.TrimRight(s, "=")
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base32 secret: %w", err)
+	}
+	return b, nil
+}
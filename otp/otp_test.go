@@ -0,0 +1,88 @@
+package otp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCodeRFC6238(t *testing.T) {
+	secrets := map[string]string{
+		SHA1:   "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		SHA256: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA====",
+		SHA512: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA=",
+	}
+	// Test vectors from RFC 6238 Appendix B
+	tests := []struct {
+		unix int64
+		want map[string]string
+	}{
+		{59, map[string]string{SHA1: "94287082", SHA256: "46119246", SHA512: "90693936"}},
+		{1111111109, map[string]string{SHA1: "07081804", SHA256: "68084774", SHA512: "25091201"}},
+		{1111111111, map[string]string{SHA1: "14050471", SHA256: "67062674", SHA512: "99943326"}},
+		{1234567890, map[string]string{SHA1: "89005924", SHA256: "91819424", SHA512: "93441116"}},
+		{2000000000, map[string]string{SHA1: "69279037", SHA256: "90698825", SHA512: "38618901"}},
+		{20000000000, map[string]string{SHA1: "65353130", SHA256: "77737706", SHA512: "47863826"}},
+	}
+	for _, tt := range tests {
+		for algorithm, want := range tt.want {
+			k := &Key{Secret: secrets[algorithm], Algorithm: algorithm, Digits: 8, Period: 30}
+			got, _, err := k.Code(time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s at %d: got %s, want %s", algorithm, tt.unix, got, want)
+			}
+		}
+	}
+}
+
+func TestCodeRemaining(t *testing.T) {
+	k := NewKey("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	_, remaining, err := k.Code(time.Unix(59, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != time.Second {
+		t.Errorf("Expected 1s remaining, got %s", remaining)
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	k, err := ParseURI("otpauth://totp/ACME%20Co:john@example.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Key{
+		Issuer:    "ACME Co",
+		Account:   "john@example.com",
+		Secret:    "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		Algorithm: SHA256,
+		Digits:    8,
+		Period:    60,
+	}
+	if *k != want {
+		t.Errorf("got %+v, want %+v", *k, want)
+	}
+
+	k, err = ParseURI("otpauth://totp/john?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Account != "john" || k.Algorithm != DefaultAlgorithm || k.Digits != DefaultDigits || k.Period != DefaultPeriod {
+		t.Errorf("Defaults not applied: %+v", *k)
+	}
+
+	for _, uri := range []string{
+		"https://example.com",
+		"otpauth://hotp/john?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		"otpauth://totp/john",
+		"otpauth://totp/john?secret=not-base32!",
+		"otpauth://totp/john?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&algorithm=MD5",
+		"otpauth://totp/john?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&digits=4",
+	} {
+		if _, err := ParseURI(uri); err == nil {
+			t.Errorf("%s: expected error", uri)
+		}
+	}
+}
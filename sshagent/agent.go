@@ -0,0 +1,219 @@
+package sshagent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// The keys are managed by the vault, clients can't change them
+	ErrReadOnly = errors.New("agent: keys are read only")
+	ErrLocked   = errors.New("agent: locked")
+	ErrNotFound = errors.New("agent: key not found")
+	ErrDenied   = errors.New("agent: use of the key is not confirmed")
+)
+
+// Key is a key offered by the agent, the private key is only loaded to
+// sign.
+type Key struct {
+	PublicKey ssh.PublicKey
+	Comment   string
+	// Load returns the private key, see ssh.ParseRawPrivateKey for the
+	// supported types.
+	Load func() (any, error)
+}
+
+// Agent is an ssh agent serving the keys returned by a function, so
+// changes of the vault are picked up without a restart. Confirmations are
+// asked one at a time, other requests are served meanwhile.
+type Agent struct {
+	mu         sync.Mutex
+	keys       func() ([]*Key, error)
+	confirm    func(k *Key) bool
+	passphrase []byte
+	// Serializes the confirmations, they share the terminal
+	confirmMu sync.Mutex
+}
+
+var _ agent.ExtendedAgent = (*Agent)(nil)
+
+// New returns an agent offering the keys, confirm is asked before each
+// signature if it's not nil.
+func New(keys func() ([]*Key, error), confirm func(k *Key) bool) *Agent {
+	return &Agent{keys: keys, confirm: confirm}
+}
+
+// List implements agent.Agent
+func (a *Agent) List() ([]*agent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase != nil {
+		// A locked agent offers no keys
+		return nil, nil
+	}
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*agent.Key, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, &agent.Key{
+			Format:  k.PublicKey.Type(),
+			Blob:    k.PublicKey.Marshal(),
+			Comment: k.Comment,
+		})
+	}
+	return out, nil
+}
+
+// Sign implements agent.Agent
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags implements agent.ExtendedAgent
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	k, err := a.signingKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if a.confirm != nil {
+		// Not under mu, so the agent can be listed or locked while the
+		// user answers
+		a.confirmMu.Lock()
+		ok := a.confirm(k)
+		a.confirmMu.Unlock()
+		if !ok {
+			return nil, ErrDenied
+		}
+		if a.locked() {
+			return nil, ErrLocked
+		}
+	}
+	signer, err := loadSigner(k)
+	if err != nil {
+		return nil, err
+	}
+	if flags == 0 {
+		return signer.Sign(rand.Reader, data)
+	}
+	var algorithm string
+	switch flags {
+	case agent.SignatureFlagRsaSha256:
+		algorithm = ssh.KeyAlgoRSASHA256
+	case agent.SignatureFlagRsaSha512:
+		algorithm = ssh.KeyAlgoRSASHA512
+	default:
+		return nil, fmt.Errorf("agent: unsupported signature flags: %d", flags)
+	}
+	as, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("agent: %s key does not support %s", key.Type(), algorithm)
+	}
+	return as.SignWithAlgorithm(rand.Reader, data, algorithm)
+}
+
+// signingKey returns the offered key matching key, unless the agent is
+// locked.
+func (a *Agent) signingKey(key ssh.PublicKey) (*Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase != nil {
+		return nil, ErrLocked
+	}
+	return a.find(key)
+}
+
+func (a *Agent) locked() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.passphrase != nil
+}
+
+func (a *Agent) find(key ssh.PublicKey) (*Key, error) {
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	wanted := key.Marshal()
+	for _, k := range keys {
+		if bytes.Equal(k.PublicKey.Marshal(), wanted) {
+			return k, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// loadSigner loads the private key and checks that it's the offered one.
+func loadSigner(k *Key) (ssh.Signer, error) {
+	priv, err := k.Load()
+	if err != nil {
+		return nil, fmt.Errorf("agent: failed to load %s: %w", k.Comment, err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(signer.PublicKey().Marshal(), k.PublicKey.Marshal()) {
+		return nil, fmt.Errorf("agent: private key of %s does not match its public key", k.Comment)
+	}
+	return signer, nil
+}
+
+// Signers implements agent.Agent, it's not supported since the private
+// keys are only loaded to sign.
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return nil, errors.New("agent: signers are not available, use Sign")
+}
+
+// Lock implements agent.Agent
+func (a *Agent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase != nil {
+		return ErrLocked
+	}
+	a.passphrase = append([]byte{}, passphrase...)
+	return nil
+}
+
+// Unlock implements agent.Agent
+func (a *Agent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase == nil {
+		return errors.New("agent: not locked")
+	}
+	if subtle.ConstantTimeCompare(a.passphrase, passphrase) != 1 {
+		return errors.New("agent: incorrect passphrase")
+	}
+	a.passphrase = nil
+	return nil
+}
+
+// Add implements agent.Agent
+func (a *Agent) Add(key agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+// Remove implements agent.Agent
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	return ErrReadOnly
+}
+
+// RemoveAll implements agent.Agent
+func (a *Agent) RemoveAll() error {
+	return ErrReadOnly
+}
+
+// Extension implements agent.ExtendedAgent
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
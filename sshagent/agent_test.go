@@ -0,0 +1,238 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func newTestKey(t *testing.T, priv any, comment string, loads *int) *Key {
+	t.Helper()
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal("Failed to create signer:", err)
+	}
+	return &Key{
+		PublicKey: signer.PublicKey(),
+		Comment:   comment,
+		Load: func() (any, error) {
+			*loads++
+			return priv, nil
+		},
+	}
+}
+
+// serve returns a client of the agent connected through a pipe.
+func serve(t *testing.T, a *Agent) agent.ExtendedAgent {
+	t.Helper()
+	server, client := net.Pipe()
+	go agent.ServeAgent(a, server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return agent.NewClient(client)
+}
+
+// dial authenticates to a local ssh server which only accepts want, with
+// the keys of the agent.
+func dial(ag agent.ExtendedAgent, want ssh.PublicKey) error {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(want.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		return err
+	}
+	config.AddHostKey(hostSigner)
+
+	// Both sides send their version first, that deadlocks on net.Pipe
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	go func() {
+		server, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer server.Close()
+		conn, chans, reqs, err := ssh.NewServerConn(server, config)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go ssh.DiscardRequests(reqs)
+		for c := range chans {
+			c.Reject(ssh.Prohibited, "no channels")
+		}
+	}()
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	conn, _, _, err := ssh.NewClientConn(client, l.Addr().String(), &ssh.ClientConfig{
+		User:            "test",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeysCallback(ag.Signers)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestAgentSignOnDemand(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edLoads, rsaLoads int
+	keys := []*Key{
+		newTestKey(t, edKey, "default/ed", &edLoads),
+		newTestKey(t, rsaKey, "default/rsa", &rsaLoads),
+	}
+	ag := serve(t, New(func() ([]*Key, error) { return keys, nil }, nil))
+
+	listed, err := ag.List()
+	if err != nil {
+		t.Fatal("Failed to list keys:", err)
+	}
+	if len(listed) != 2 || listed[0].Comment != "default/ed" || listed[1].Comment != "default/rsa" {
+		t.Fatal("Unexpected keys:", listed)
+	}
+	if edLoads != 0 || rsaLoads != 0 {
+		t.Fatal("Keys are loaded by List")
+	}
+
+	// The rsa key is signed with rsa-sha2-*, through SignWithFlags
+	if err := dial(ag, keys[1].PublicKey); err != nil {
+		t.Fatal("Failed to authenticate with rsa key:", err)
+	}
+	if err := dial(ag, keys[0].PublicKey); err != nil {
+		t.Fatal("Failed to authenticate with ed25519 key:", err)
+	}
+	if edLoads != 1 || rsaLoads == 0 {
+		t.Fatal("Unexpected number of loads:", edLoads, rsaLoads)
+	}
+
+	if err := ag.Add(agent.AddedKey{PrivateKey: edKey}); err == nil {
+		t.Fatal("Expected error for adding keys")
+	}
+}
+
+func TestAgentConfirm(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loads int
+	k := newTestKey(t, priv, "default/ed", &loads)
+	var asked []string
+	allow := false
+	ag := serve(t, New(func() ([]*Key, error) { return []*Key{k}, nil }, func(k *Key) bool {
+		asked = append(asked, k.Comment)
+		return allow
+	}))
+
+	if _, err := ag.Sign(k.PublicKey, []byte("data")); err == nil {
+		t.Fatal("Expected error for denied signature")
+	}
+	if loads != 0 {
+		t.Fatal("Key is loaded without confirmation")
+	}
+	allow = true
+	sig, err := ag.Sign(k.PublicKey, []byte("data"))
+	if err != nil {
+		t.Fatal("Failed to sign:", err)
+	}
+	if err := k.PublicKey.Verify([]byte("data"), sig); err != nil {
+		t.Fatal("Invalid signature:", err)
+	}
+	if len(asked) != 2 || asked[0] != "default/ed" {
+		t.Fatal("Unexpected confirmations:", asked)
+	}
+}
+
+func TestAgentLock(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loads int
+	k := newTestKey(t, priv, "default/ed", &loads)
+	ag := serve(t, New(func() ([]*Key, error) { return []*Key{k}, nil }, nil))
+
+	if err := ag.Lock([]byte("pp")); err != nil {
+		t.Fatal("Failed to lock:", err)
+	}
+	if keys, err := ag.List(); err != nil || len(keys) != 0 {
+		t.Fatal("Locked agent lists keys:", keys, err)
+	}
+	if _, err := ag.Sign(k.PublicKey, []byte("data")); err == nil {
+		t.Fatal("Locked agent signs")
+	}
+	if err := ag.Unlock([]byte("wrong")); err == nil {
+		t.Fatal("Expected error for wrong passphrase")
+	}
+	if err := ag.Unlock([]byte("pp")); err != nil {
+		t.Fatal("Failed to unlock:", err)
+	}
+	if _, err := ag.Sign(k.PublicKey, []byte("data")); err != nil {
+		t.Fatal("Failed to sign:", err)
+	}
+}
+
+func TestAgentConfirmDoesNotBlock(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loads int
+	k := newTestKey(t, priv, "default/ed", &loads)
+	asking, answer := make(chan struct{}), make(chan bool)
+	a := New(func() ([]*Key, error) { return []*Key{k}, nil }, func(k *Key) bool {
+		asking <- struct{}{}
+		return <-answer
+	})
+	signed := make(chan error, 1)
+	go func() {
+		_, err := serve(t, a).Sign(k.PublicKey, []byte("data"))
+		signed <- err
+	}()
+	<-asking
+
+	// Other clients are served while the user is asked
+	ag := serve(t, a)
+	if keys, err := ag.List(); err != nil || len(keys) != 1 {
+		t.Fatal("Failed to list keys while confirming:", keys, err)
+	}
+	if err := ag.Lock([]byte("pp")); err != nil {
+		t.Fatal("Failed to lock while confirming:", err)
+	}
+	answer <- true
+	if err := <-signed; err == nil {
+		t.Fatal("Agent locked during the confirmation signs")
+	}
+	if loads != 0 {
+		t.Fatal("Key is loaded after the agent is locked")
+	}
+}
@@ -0,0 +1,6 @@
+package version
+
+// Version of mypass, set at build time with
+//
+//	go build -ldflags "-X github.com/riadafridishibly/mypass/version.Version=v1.2.3"
+var Version = "dev"
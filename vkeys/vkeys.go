@@ -7,4 +7,15 @@ const (
 	PublicKeys      = "public_keys_slice"
 	Password        = "password"
 	CachedPassword  = "cached_password"
+	SkipDecryption  = "skip_decryption"
+	// Duration, after which copied secrets are cleared from the clipboard
+	ClipboardTimeout = "clipboard_timeout"
+	// Number of database backups to keep
+	Backups = "backups"
+	// Duration to wait for another process to release the database
+	LockTimeout = "lock_timeout"
+	// Number of previous passwords kept for each item
+	PasswordHistory = "password_history"
+	// Duration, after which trashed items are removed permanently
+	TrashRetention = "trash_retention"
 )